@@ -0,0 +1,33 @@
+package libip
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bakedSpaceTime/binip/libip/alert"
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+)
+
+// AlertCheck samples utilisation and fires any newly crossed alerts. Every
+// change runs it already; running it periodically as well, for example from
+// cron, keeps the history sampled on days without changes.
+func AlertCheck(c *config.Config) error {
+	d := db.New(c)
+	defer d.Close()
+
+	alerts, err := alert.Run(d, c)
+	for _, a := range alerts {
+		fmt.Printf("[%s] %s\n", a.Kind, a.Message)
+	}
+	return err
+}
+
+// monitor runs the alert check after a change to the address plan. Failures
+// are reported but do not fail the change itself.
+func monitor(c *config.Config, d *db.Db) {
+	if _, err := alert.Run(d, c); err != nil {
+		fmt.Fprintln(os.Stderr, styles.WarnStyle.Render(fmt.Sprintf("warning: alert check: %s", err)))
+	}
+}
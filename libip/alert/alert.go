@@ -0,0 +1,155 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/report"
+)
+
+// Alert kinds
+const (
+	Utilisation   = "utilisation"
+	Fragmentation = "fragmentation"
+	Forecast      = "forecast"
+)
+
+// Alert is raised when a network crosses a threshold or forecast horizon
+type Alert struct {
+	Network string       `json:"network"`
+	Kind    string       `json:"kind"`
+	Message string       `json:"message"`
+	Time    time.Time    `json:"time"`
+	Usage   report.Usage `json:"usage"`
+}
+
+// Run adds today's utilisation to the history, then raises and delivers
+// alerts from it. Sampling happens here, after every change and on the
+// periodic check, so that reports and the TUI only ever read.
+func Run(d *db.Db, c *config.Config) ([]Alert, error) {
+	usages, err := report.Usages(d)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := report.RecordSamples(d, usages, now); err != nil {
+		return nil, err
+	}
+	if err := report.AttachForecasts(d, usages, c.Alerts.ForecastModel, now); err != nil {
+		return nil, err
+	}
+	alerts, err := Check(d, c, usages)
+	if err != nil {
+		return nil, err
+	}
+	return alerts, Notify(c.Alerts, alerts)
+}
+
+// Check compares each network's utilisation against the configured limits.
+// Alerts fire only when a limit is newly crossed; the raised state is stored
+// so that a network staying over a limit does not alert on every check, and
+// is cleared once it drops back below.
+func Check(d *db.Db, c *config.Config, usages []report.Usage) ([]Alert, error) {
+	var alerts []Alert
+	now := time.Now()
+	for _, u := range usages {
+		if u.Kind != "network" {
+			continue
+		}
+		state, err := d.AlertState(u.Network)
+		if err != nil {
+			return nil, err
+		}
+
+		raised := map[string]string{}
+		if u.Percent() > c.Thresholds.Utilisation {
+			raised[Utilisation] = fmt.Sprintf("%s is %.1f%% utilised (threshold %.0f%%)",
+				u.Network, u.Percent(), c.Thresholds.Utilisation)
+		}
+		if u.Fragmentation > c.Thresholds.Fragmentation {
+			raised[Fragmentation] = fmt.Sprintf("%s fragmentation is %.2f (threshold %.2f)",
+				u.Network, u.Fragmentation, c.Thresholds.Fragmentation)
+		}
+		if u.Forecast != nil && u.Forecast.DaysToFull <= c.Alerts.ForecastHorizon {
+			raised[Forecast] = fmt.Sprintf("%s: %s", u.Network, u.Forecast)
+		}
+
+		next := make(map[string]bool)
+		for kind, msg := range raised {
+			next[kind] = true
+			if !state[kind] {
+				alerts = append(alerts, Alert{Network: u.Network, Kind: kind, Message: msg, Time: now, Usage: u})
+			}
+		}
+		if err := d.SetAlertState(u.Network, next); err != nil {
+			return nil, err
+		}
+	}
+	return alerts, nil
+}
+
+// Notify delivers alerts to every configured destination. Delivery carries on
+// past failures and returns them joined.
+func Notify(c config.Alerts, alerts []Alert) error {
+	var errs []error
+	for _, a := range alerts {
+		if c.Log {
+			log.Printf("binip alert [%s] %s", a.Kind, a.Message)
+		}
+		body, err := json.Marshal(a)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if c.Command != "" {
+			if err := runCommand(c.Command, a, body); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if c.Webhook != "" {
+			if err := postWebhook(c.Webhook, body); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runCommand runs the alert hook through the shell with the alert as JSON on
+// stdin and its main fields in the environment
+func runCommand(command string, a Alert, body []byte) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	cmd.Env = append(os.Environ(),
+		"BINIP_ALERT_NETWORK="+a.Network,
+		"BINIP_ALERT_KIND="+a.Kind,
+		"BINIP_ALERT_MESSAGE="+a.Message,
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("alert command: %s", err)
+	}
+	return nil
+}
+
+func postWebhook(url string, body []byte) error {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("alert webhook: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("alert webhook: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"net/netip"
+	"strings"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// mapColouring selects what the address map cells are coloured by
+type mapColouring uint
+
+const (
+	colourByState mapColouring = iota
+	colourByRole
+)
+
+func (c mapColouring) String() string {
+	switch c {
+	case colourByState:
+		return "state"
+	case colourByRole:
+		return "role"
+	default:
+		return "unknown"
+	}
+}
+
+// mapGrid is the layout of one level of the address map. Each cell covers
+// 2^cellBits addresses, so a cellBits of zero means one address per cell.
+type mapGrid struct {
+	prefix   netip.Prefix
+	cellBits int
+	cols     int
+	rows     int
+}
+
+// mapCell summarises the records falling within one cell of the grid
+type mapCell struct {
+	prefix netip.Prefix
+	counts map[record.State]int
+	record *record.Record // set when the cell is a single recorded address
+}
+
+// mapPrefix returns the prefix shown at the current map level
+func (m *mainModel) mapPrefix() netip.Prefix {
+	if len(m.mapStack) > 0 {
+		return m.mapStack[len(m.mapStack)-1]
+	}
+	return m.network.Prefix
+}
+
+// mapLayout sizes the grid for the current level from the terminal size.
+// Columns and rows are powers of two so that every cell is an aligned block.
+func (m *mainModel) mapLayout() mapGrid {
+	width := m.width
+	if width == 0 {
+		width = 80
+	}
+	availCols := max((width-20)/2, 4)
+	// Rows left by the scale line, the cursor info and the legend
+	availRows := max(m.bodyHeight()-5, 4)
+	colBits := min(bits.Len(uint(availCols))-1, 6)
+	rowBits := bits.Len(uint(availRows)) - 1
+
+	p := m.mapPrefix()
+	g := mapGrid{prefix: p}
+	hostBits := p.Addr().BitLen() - p.Bits()
+	if hostBits <= colBits+rowBits {
+		colBits = min(colBits, hostBits)
+		g.cols = 1 << colBits
+		g.rows = 1 << (hostBits - colBits)
+		return g
+	}
+	g.cellBits = hostBits - colBits - rowBits
+	g.cols, g.rows = 1<<colBits, 1<<rowBits
+	return g
+}
+
+// cellPrefix returns the block of addresses covered by cell i
+func (g mapGrid) cellPrefix(i int) netip.Prefix {
+	off := new(big.Int).Lsh(big.NewInt(int64(i)), uint(g.cellBits))
+	// Cells lie within the prefix by construction
+	addr, _ := record.AddrAt(g.prefix, off)
+	return netip.PrefixFrom(addr, addr.BitLen()-g.cellBits)
+}
+
+// mapCells buckets the records of the current network into the grid's cells
+func (m *mainModel) mapCells(g mapGrid) []mapCell {
+	cells := make([]mapCell, g.cols*g.rows)
+	for i := range cells {
+		cells[i] = mapCell{prefix: g.cellPrefix(i), counts: make(map[record.State]int)}
+	}
+	for i, r := range m.records {
+		if !g.prefix.Contains(r.Addr) {
+			continue
+		}
+		idx := record.AddrOffset(g.prefix, r.Addr)
+		idx.Rsh(idx, uint(g.cellBits))
+		c := &cells[idx.Int64()]
+		c.counts[r.State]++
+		if g.cellBits == 0 {
+			c.record = &m.records[i]
+		}
+	}
+	return cells
+}
+
+// cellStyle picks the colour of a cell for the active colouring
+func (m *mainModel) cellStyle(c mapCell) lipgloss.Style {
+	switch m.mapColour {
+	case colourByRole:
+		role := m.network.RoleOf(c.prefix.Addr())
+		if role == record.RoleNone {
+			return styles.EmptyCell()
+		}
+		return styles.RoleCell(role.String())
+	default:
+		// Blocks take the colour of their most common state
+		best, most := record.Free, 0
+		for _, st := range record.States {
+			if c.counts[st] > most {
+				best, most = st, c.counts[st]
+			}
+		}
+		if most == 0 {
+			return styles.EmptyCell()
+		}
+		return styles.StateCell(best.String())
+	}
+}
+
+// moveMapCursor moves the map cursor by dx columns and dy rows
+func (m *mainModel) moveMapCursor(dx, dy int) {
+	g := m.mapLayout()
+	col := m.mapCursor%g.cols + dx
+	row := m.mapCursor/g.cols + dy
+	col = max(0, min(col, g.cols-1))
+	row = max(0, min(row, g.rows-1))
+	m.mapCursor = row*g.cols + col
+}
+
+// mapView renders the address space of the current level as a grid
+func (m *mainModel) mapView() string {
+	g := m.mapLayout()
+	cells := m.mapCells(g)
+	if m.mapCursor >= len(cells) {
+		m.mapCursor = len(cells) - 1
+	}
+
+	scale := "1 address per cell"
+	if g.cellBits > 0 {
+		scale = fmt.Sprintf("1 /%d per cell", g.prefix.Addr().BitLen()-g.cellBits)
+	}
+	info := styles.InfoStyle.Render(fmt.Sprintf("address map · %s · colour by %s", scale, m.mapColour))
+
+	labelWidth := mapLabelWidth(cells)
+	var rows []string
+	for row := 0; row < g.rows; row++ {
+		var line strings.Builder
+		start := cells[row*g.cols].prefix.Addr().String()
+		line.WriteString(styles.InfoStyle.Render(fmt.Sprintf("%-*s ", labelWidth, start)))
+		for col := 0; col < g.cols; col++ {
+			i := row*g.cols + col
+			text := "  "
+			style := m.cellStyle(cells[i])
+			if i == m.mapCursor {
+				text = "[]"
+				style = style.Inherit(styles.MapCursorStyle)
+			}
+			line.WriteString(style.Render(text))
+		}
+		rows = append(rows, line.String())
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		info,
+		"",
+		strings.Join(rows, "\n"),
+		"",
+		m.mapCursorInfo(g, cells[m.mapCursor]),
+		m.mapLegend(),
+	)
+}
+
+// mapLabelWidth is the width of the start addresses labelling the grid rows
+func mapLabelWidth(cells []mapCell) int {
+	return len(cells[len(cells)-1].prefix.Addr().String())
+}
+
+// mapCursorInfo describes the address or block under the cursor
+func (m *mainModel) mapCursorInfo(g mapGrid, c mapCell) string {
+	role := m.network.RoleOf(c.prefix.Addr())
+	if g.cellBits == 0 {
+		parts := []string{c.prefix.Addr().String()}
+		if c.record != nil {
+			parts = append(parts, stateBadge(c.record.State), c.record.Hostname)
+		} else {
+			parts = append(parts, styles.InfoStyle.Render("unrecorded"))
+		}
+		if role != record.RoleNone {
+			parts = append(parts, roleLabel(role))
+		}
+		return strings.Join(parts, "  ")
+	}
+
+	used := 0
+	for _, n := range c.counts {
+		used += n
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(g.cellBits))
+	return fmt.Sprintf("%s  %d/%s recorded", c.prefix, used, size)
+}
+
+// mapLegend explains the cell colours of the active colouring
+func (m *mainModel) mapLegend() string {
+	var items []string
+	switch m.mapColour {
+	case colourByRole:
+		for _, r := range record.Roles {
+			items = append(items, styles.RoleCell(r.String()).Render("  ")+" "+r.String())
+		}
+	default:
+		for _, st := range record.States {
+			items = append(items, styles.StateCell(st.String()).Render("  ")+" "+st.String())
+		}
+	}
+	items = append(items, styles.EmptyCell().Render("  ")+" none")
+	return strings.Join(items, "  ")
+}
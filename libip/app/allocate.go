@@ -0,0 +1,172 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// allocateForm binds the inputs of the bulk allocation form
+type allocateForm struct {
+	count      string
+	hostname   string
+	first      string
+	contiguous bool
+	role       record.Role
+	confirmed  bool
+
+	// preview is the planned allocation shown before confirming, for the
+	// request planned; previewSeq identifies the latest change to plan
+	preview    string
+	planned    db.AllocateRequest
+	previewSeq int
+}
+
+// previewRows is the number of planned addresses listed before the rest are
+// summarised
+const previewRows = 12
+
+// previewDelay is how long the allocation form must be left unchanged before
+// its preview is planned, so that typing does not plan on every key
+const previewDelay = 300 * time.Millisecond
+
+// allocateManyForm asks how many addresses to allocate and how to name them,
+// then previews the addresses before they are assigned
+func (m *mainModel) allocateManyForm() *huh.Form {
+	m.formAlloc = allocateForm{count: "1", first: "1", preview: "Planning…"}
+	f := &m.formAlloc
+
+	roles := []huh.Option[record.Role]{huh.NewOption("any allocatable", record.RoleNone)}
+	for _, ro := range record.Roles {
+		if len(m.network.RangesWithRole(ro)) > 0 {
+			roles = append(roles, huh.NewOption(ro.String(), ro))
+		}
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Count").
+				Description("Addresses to allocate; all are assigned or none").
+				Value(&f.count).
+				Validate(allocationCount),
+			huh.NewInput().
+				Title("Hostname").
+				Placeholder("node-{n:02}").
+				Description("{n} or {n:02} numbers each hostname; blank leaves them unnamed").
+				Value(&f.hostname).
+				Validate(func(s string) error {
+					_, err := record.ExpandHostname(s, 1)
+					return err
+				}),
+			huh.NewInput().
+				Title("First number").
+				Value(&f.first).
+				Validate(func(s string) error {
+					_, err := strconv.Atoi(strings.TrimSpace(s))
+					return err
+				}),
+			huh.NewSelect[bool]().
+				Title("Layout").
+				Inline(true).
+				Options(huh.NewOption("first free", false), huh.NewOption("contiguous block", true)).
+				Value(&f.contiguous),
+			huh.NewSelect[record.Role]().
+				Title("Range").
+				Inline(true).
+				Options(roles...).
+				Value(&f.role),
+		).Title("Allocate Addresses"),
+		huh.NewGroup(
+			huh.NewNote().
+				Title("Preview").
+				DescriptionFunc(func() string { return f.preview }, &f.preview),
+			huh.NewConfirm().
+				Title("Allocate these addresses?").
+				Affirmative("Allocate").
+				Negative("Cancel").
+				Value(&f.confirmed).
+				Validate(func(ok bool) error {
+					if !ok {
+						return nil
+					}
+					_, err := m.db.PlanAllocation(m.network.Name, m.allocateRequest())
+					return err
+				}),
+		),
+	)
+}
+
+// allocationCount validates a whole number of addresses to allocate
+func allocationCount(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 || n > db.MaxAllocation {
+		return fmt.Errorf("enter a whole number from 1 to %d", db.MaxAllocation)
+	}
+	return nil
+}
+
+// allocateRequest builds the request described by the allocation form
+func (m *mainModel) allocateRequest() db.AllocateRequest {
+	f := m.formAlloc
+	count, _ := strconv.Atoi(strings.TrimSpace(f.count))
+	first, _ := strconv.Atoi(strings.TrimSpace(f.first))
+	return db.AllocateRequest{
+		Count:      count,
+		Contiguous: f.contiguous,
+		Hostname:   strings.TrimSpace(f.hostname),
+		First:      first,
+		Role:       f.role,
+	}
+}
+
+// schedulePreview waits for the allocation form to settle after a change
+// before planning its preview
+func (m *mainModel) schedulePreview() tea.Cmd {
+	f := &m.formAlloc
+	if req := m.allocateRequest(); req != f.planned {
+		f.planned = req
+		f.previewSeq++
+		seq := f.previewSeq
+		return tea.Tick(previewDelay, func(time.Time) tea.Msg { return previewTickMsg{seq: seq} })
+	}
+	return nil
+}
+
+// planPreview lists the addresses the allocation form would assign
+func (m *mainModel) planPreview(seq int) tea.Cmd {
+	network, req := m.network.Name, m.formAlloc.planned
+	return func() tea.Msg {
+		records, err := m.db.PlanAllocation(network, req)
+		if err != nil {
+			return previewPlannedMsg{seq: seq, text: styles.ErrorStyle.Render(err.Error())}
+		}
+		var lines []string
+		for _, r := range records[:min(len(records), previewRows)] {
+			lines = append(lines, fmt.Sprintf("%-16s %s", r.ID(), r.Hostname))
+		}
+		if len(records) > previewRows {
+			last := records[len(records)-1]
+			lines = append(lines, fmt.Sprintf("… %d more, up to %s %s", len(records)-previewRows, last.ID(), last.Hostname))
+		}
+		return previewPlannedMsg{seq: seq, text: strings.Join(lines, "\n")}
+	}
+}
+
+// allocateMany assigns several addresses of the current network as one
+// undoable operation
+func (m *mainModel) allocateMany(req db.AllocateRequest) tea.Cmd {
+	network := m.network.Name
+	return func() tea.Msg {
+		records, entries, err := m.db.AllocateMany(network, req)
+		cs := changeSet{fmt.Sprintf("allocate %d addresses", req.Count), entries}
+		return recordsAllocatedMsg{records: records, change: cs, err: err}
+	}
+}
@@ -0,0 +1,286 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// Bulk operations offered by the bulk form
+const (
+	bulkFields     = "fields"
+	bulkAddTags    = "add tags"
+	bulkRemoveTags = "remove tags"
+	bulkState      = "state"
+	bulkRelease    = "release"
+	bulkMove       = "move"
+)
+
+// bulkForm binds the inputs of the bulk form
+type bulkForm struct {
+	op        string
+	fields    string
+	tags      string
+	state     record.State
+	moveTo    string
+	confirmed bool
+}
+
+// bulkRun is a bulk change being applied
+type bulkRun struct {
+	change db.BulkChange
+	done   int
+	total  int
+}
+
+// toggleMark marks or unmarks the record under the cursor
+func (m *mainModel) toggleMark() {
+	r, ok := m.selectedRecord()
+	if !ok {
+		return
+	}
+	if m.marked[r.ID()] {
+		delete(m.marked, r.ID())
+		return
+	}
+	if m.marked == nil {
+		m.marked = make(map[string]bool)
+	}
+	m.marked[r.ID()] = true
+}
+
+// extendMarks marks the record under the cursor and the one it moves to, so
+// holding the key marks a range
+func (m *mainModel) extendMarks(step int) {
+	if m.marked == nil {
+		m.marked = make(map[string]bool)
+	}
+	if r, ok := m.selectedRecord(); ok {
+		m.marked[r.ID()] = true
+	}
+	m.cursor += step
+	m.clampCursor()
+	if r, ok := m.selectedRecord(); ok {
+		m.marked[r.ID()] = true
+	}
+}
+
+// markAll marks every record passing the filter and search, or clears the
+// marks when they are all marked already
+func (m *mainModel) markAll() {
+	visible := m.visibleRecords()
+	all := len(visible) > 0
+	for _, r := range visible {
+		all = all && m.marked[r.ID()]
+	}
+	if all {
+		m.marked = nil
+		return
+	}
+	if m.marked == nil {
+		m.marked = make(map[string]bool)
+	}
+	for _, r := range visible {
+		m.marked[r.ID()] = true
+	}
+}
+
+// bulkTargets returns the IDs of the marked records in list order, or the
+// record under the cursor when none are marked
+func (m *mainModel) bulkTargets() []string {
+	var ids []string
+	for _, r := range m.records {
+		if m.marked[r.ID()] {
+			ids = append(ids, r.ID())
+		}
+	}
+	if len(ids) == 0 {
+		if r, ok := m.selectedRecord(); ok {
+			ids = append(ids, r.ID())
+		}
+	}
+	return ids
+}
+
+// bulkRecordForm asks for the change to apply to the marked records and
+// confirms it with a summary
+func (m *mainModel) bulkRecordForm() *huh.Form {
+	m.formBulk = bulkForm{op: bulkAddTags, state: record.Allocated}
+	count := len(m.bulkTargets())
+	f := &m.formBulk
+
+	ops := []huh.Option[string]{
+		huh.NewOption("Set custom fields", bulkFields),
+		huh.NewOption("Add tags", bulkAddTags),
+		huh.NewOption("Remove tags", bulkRemoveTags),
+		huh.NewOption("Change state", bulkState),
+		huh.NewOption("Release to quarantine", bulkRelease),
+		huh.NewOption("Move to another network", bulkMove),
+	}
+	states := make([]huh.Option[record.State], len(record.States))
+	for i, st := range record.States {
+		states[i] = huh.NewOption(st.String(), st)
+	}
+	var networks []huh.Option[string]
+	if all, err := m.db.ListNetworks(); err == nil {
+		for _, n := range all {
+			if n.Name != m.network.Name {
+				networks = append(networks, huh.NewOption(fmt.Sprintf("%s (%s)", n.Name, n.Prefix), n.Name))
+			}
+		}
+	}
+	if len(networks) > 0 {
+		f.moveTo = networks[0].Value
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(fmt.Sprintf("Bulk change of %d records", count)).
+				Options(ops...).
+				Value(&f.op),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Custom fields").
+				Description("NAME=VALUE, comma separated; an empty value clears the field").
+				Value(&f.fields).
+				Validate(func(s string) error {
+					_, err := m.parseBulkFields(s)
+					return err
+				}),
+		).WithHideFunc(func() bool { return f.op != bulkFields }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Tags").
+				Description("Comma or space separated").
+				Value(&f.tags).
+				Validate(func(s string) error {
+					tags := record.ParseTags(s)
+					if len(tags) == 0 {
+						return fmt.Errorf("enter at least one tag")
+					}
+					return m.network.ValidateRecord(record.Record{Tags: tags})
+				}),
+		).WithHideFunc(func() bool { return f.op != bulkAddTags && f.op != bulkRemoveTags }),
+		huh.NewGroup(
+			huh.NewSelect[record.State]().
+				Title("New state").
+				Options(states...).
+				Value(&f.state),
+		).WithHideFunc(func() bool { return f.op != bulkState }),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Move to network").
+				Description("Each record keeps its offset from the start of the prefix").
+				Options(networks...).
+				Value(&f.moveTo).
+				Validate(func(s string) error {
+					if s == "" {
+						return fmt.Errorf("there is no other network to move to")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return f.op != bulkMove }),
+		huh.NewGroup(
+			huh.NewConfirm().
+				TitleFunc(func() string {
+					return fmt.Sprintf("Apply to %d records?\n%s", count, styles.InfoStyle.Render(m.bulkChange().String()))
+				}, []any{&f.op, &f.fields, &f.tags, &f.state, &f.moveTo}).
+				Description("Applied in one transaction: if any record is rejected none are changed").
+				Affirmative("Apply").
+				Negative("Cancel").
+				Value(&f.confirmed),
+		),
+	)
+}
+
+// parseBulkFields parses NAME=VALUE pairs, checking each against the
+// network's field schema
+func (m *mainModel) parseBulkFields(s string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not NAME=VALUE", strings.TrimSpace(pair))
+		}
+		name, v = strings.TrimSpace(name), strings.TrimSpace(v)
+		f, ok := m.network.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("network %s has no field %q", m.network.Name, name)
+		}
+		if v != "" {
+			if err := f.Check(v); err != nil {
+				return nil, err
+			}
+		}
+		fields[name] = v
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("enter at least one field")
+	}
+	return fields, nil
+}
+
+// bulkChange builds the change described by the bulk form
+func (m *mainModel) bulkChange() db.BulkChange {
+	var c db.BulkChange
+	switch f := m.formBulk; f.op {
+	case bulkFields:
+		c.Fields, _ = m.parseBulkFields(f.fields)
+	case bulkAddTags:
+		c.AddTags = record.ParseTags(f.tags)
+	case bulkRemoveTags:
+		c.RemoveTags = record.ParseTags(f.tags)
+	case bulkState:
+		c.State = &f.state
+	case bulkRelease:
+		st := record.Quarantined
+		c.State = &st
+	case bulkMove:
+		c.MoveTo = f.moveTo
+	}
+	return c
+}
+
+// applyBulk applies a change to records of the current network as one
+// undoable operation, reporting progress as each record is done
+func (m *mainModel) applyBulk(ids []string, c db.BulkChange) tea.Cmd {
+	network := m.network.Name
+	progress := make(chan int, len(ids)) // Never blocks the transaction
+	m.bulk = &bulkRun{change: c, total: len(ids)}
+	apply := func() tea.Msg {
+		defer close(progress)
+		label := fmt.Sprintf("bulk %s (%d records)", c, len(ids))
+		entries, err := m.db.BulkUpdate(network, ids, c, func(done int) { progress <- done })
+		return bulkAppliedMsg{count: len(ids), change: changeSet{label, entries}, err: err}
+	}
+	return tea.Batch(apply, waitBulkProgress(progress))
+}
+
+// waitBulkProgress waits for the next progress report of a bulk change
+func waitBulkProgress(progress chan int) tea.Cmd {
+	return func() tea.Msg {
+		done, ok := <-progress
+		if !ok {
+			return nil
+		}
+		return bulkProgressMsg{done: done, progress: progress}
+	}
+}
+
+// bulkStatus renders the progress of the running bulk change
+func (m *mainModel) bulkStatus() string {
+	const width = 20
+	filled := width * m.bulk.done / max(m.bulk.total, 1)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	return styles.InfoStyle.Render(fmt.Sprintf("Applying %s %s %d/%d", m.bulk.change, bar, m.bulk.done, m.bulk.total))
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyText copies text to the clipboard and reports what was copied
+func copyText(what, text string) tea.Cmd {
+	return copyAs(what+" "+text, text)
+}
+
+// copyAs copies text to the clipboard, reporting it by its description. Over
+// SSH, or where there is no system clipboard, the text is sent to the
+// terminal as an OSC52 sequence so that it lands on the user's own
+// clipboard.
+func copyAs(desc, text string) tea.Cmd {
+	return func() tea.Msg {
+		via := ""
+		if remoteSession() || clipboard.Unsupported || clipboard.WriteAll(text) != nil {
+			if err := writeOSC52(text); err != nil {
+				return errorMsg{context: "copy " + desc, err: err}
+			}
+			via = " via the terminal"
+		}
+		return statusMsg{severity: severitySuccess, text: "Copied " + desc + via}
+	}
+}
+
+// remoteSession reports whether the TUI runs over SSH, where the system
+// clipboard belongs to the remote host rather than the user
+func remoteSession() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+// writeOSC52 asks the terminal to set its clipboard, wrapping the sequence
+// for tmux and screen so that it reaches the outer terminal
+func writeOSC52(text string) error {
+	seq := osc52.New(text)
+	switch term := os.Getenv("TERM"); {
+	case os.Getenv("TMUX") != "" || strings.HasPrefix(term, "tmux"):
+		seq = seq.Tmux()
+	case strings.HasPrefix(term, "screen"):
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(os.Stderr)
+	return err
+}
+
+// recordLine formats a record as one tab-separated line: address, state,
+// hostname, MAC, owner, description and tags
+func recordLine(r record.Record) string {
+	return strings.Join([]string{
+		r.ID(), r.State.String(), r.Hostname, r.MAC, r.Owner, r.Description, strings.Join(r.Tags, ","),
+	}, "\t")
+}
+
+// recordCIDR is the record's address with the prefix length of its network
+func recordCIDR(n record.Network, addr netip.Addr) string {
+	return netip.PrefixFrom(addr, n.Prefix.Bits()).String()
+}
+
+// copyRecord copies a record in the format of a copy action
+func (m *mainModel) copyRecord(action string, r record.Record) tea.Cmd {
+	switch action {
+	case "copy_line":
+		return copyAs("line of "+r.ID(), recordLine(r))
+	case "copy_cidr":
+		return copyText("address", recordCIDR(m.network, r.Addr))
+	case "copy_json":
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return func() tea.Msg { return errorMsg{context: "copy JSON", err: err} }
+		}
+		return copyAs("JSON of "+r.ID(), string(out))
+	}
+	return copyText("address", r.ID())
+}
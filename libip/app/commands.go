@@ -1,13 +1,24 @@
 package app
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/alert"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/report"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// saveNetworkPrefix saves the network prefix to the database asynchronously
+// saveNetworkPrefix saves the network prefix to the database asynchronously,
+// applying the selected subnet template
 func (m *mainModel) saveNetworkPrefix(prefix string) tea.Cmd {
+	template := m.formTemplate
 	return func() tea.Msg {
-		err := m.db.SetNetwork(prefix)
+		err := m.db.SetNetwork(prefix, template)
 		return dbOperationCompleteMsg{
 			operation: "save",
 			success:   err == nil,
@@ -20,67 +31,197 @@ func (m *mainModel) saveNetworkPrefix(prefix string) tea.Cmd {
 // loadOperationalData loads initial data when entering operational state
 func (m *mainModel) loadOperationalData() tea.Cmd {
 	return func() tea.Msg {
-		// Currently just transition to list view
-		// TODO: Load initial data when CRUD UI is designed
-		return enterListViewMsg{}
+		rev, err := m.db.Revision()
+		if err != nil {
+			return errorMsg{context: "loading revision", err: err}
+		}
+		s, err := m.db.GetSession()
+		if err != nil {
+			return errorMsg{context: "loading session", err: err}
+		}
+		n, err := m.db.GetNetworkByName(s.Network)
+		if err != nil {
+			n, err = m.db.GetNetworkByName(record.DefaultNetwork)
+		}
+		if err != nil {
+			return errorMsg{context: "loading network", err: err}
+		}
+		searches, err := m.loadSavedSearches()
+		if err != nil {
+			return errorMsg{context: "loading saved searches", err: err}
+		}
+		return networkLoadedMsg{network: n, searches: searches, session: s, revision: rev}
 	}
 }
 
-// === Template CRUD Commands ===
-// These are placeholders for future implementation
+// === CRUD Commands ===
 
-// loadRecordList loads all records from the database
+// loadRecordList loads the current network and its records from the
+// database, reconstructing them as of the time travel moment if one is set
 func (m *mainModel) loadRecordList() tea.Cmd {
+	network, at := m.network.Name, m.at
+	return func() tea.Msg {
+		var msg recordsLoadedMsg
+		var err error
+		if at != nil {
+			msg.network, err = m.db.NetworkAt(network, *at)
+		} else {
+			msg.network, err = m.db.GetNetworkByName(network)
+		}
+		if err != nil {
+			return errorMsg{context: "loading network", err: err}
+		}
+		if at != nil {
+			msg.records, err = m.db.RecordsAt(network, *at)
+		} else {
+			msg.records, err = m.db.ListRecords(network)
+		}
+		if err != nil {
+			return errorMsg{context: "loading records", err: err}
+		}
+		return msg
+	}
+}
+
+// loadUtilisation computes the utilisation tree of every network
+func (m *mainModel) loadUtilisation() tea.Cmd {
 	return func() tea.Msg {
-		// TODO: Implement when CRUD UI is designed
-		// records, err := m.db.ListRecords()
-		// if err != nil {
-		//     return errorMsg{context: "loading records", err: err}
-		// }
-		// return recordsLoadedMsg{records: records}
-		return statusMsg("Records loaded")
+		usages, err := report.Load(m.db, m.config.Alerts.ForecastModel)
+		if err != nil {
+			return errorMsg{context: "loading utilisation", err: err}
+		}
+		return utilisationLoadedMsg{usages: usages}
 	}
 }
 
 // loadRecordDetail loads a single record's details
 func (m *mainModel) loadRecordDetail(id string) tea.Cmd {
+	network, at := m.network.Name, m.at
 	return func() tea.Msg {
-		// TODO: Implement when CRUD UI is designed
-		// record, err := m.db.GetRecord(id)
-		// if err != nil {
-		//     return errorMsg{context: "loading record detail", err: err}
-		// }
-		// return recordLoadedMsg{record: record}
-		return statusMsg("Record detail loaded: " + id)
+		if at != nil {
+			r, err := m.db.RecordAt(network, id, *at)
+			if err != nil {
+				return errorMsg{context: "loading record detail", err: err}
+			}
+			history, err := m.db.ListAudit(db.AuditFilter{Network: network, Record: id, Until: *at})
+			if err != nil {
+				return errorMsg{context: "loading record history", err: err}
+			}
+			return recordLoadedMsg{record: r, history: history}
+		}
+
+		r, err := m.db.GetRecord(network, id)
+		if err != nil {
+			return errorMsg{context: "loading record detail", err: err}
+		}
+		msg := recordLoadedMsg{record: r}
+		samples, err := m.db.ListSamples(network)
+		if err != nil {
+			return errorMsg{context: "loading record detail", err: err}
+		}
+		if f, ok := report.Project(samples, m.config.Alerts.ForecastModel, time.Now()); ok {
+			msg.forecast = &f
+		}
+		msg.history, err = m.db.ListAudit(db.AuditFilter{Network: network, Record: id})
+		if err != nil {
+			return errorMsg{context: "loading record history", err: err}
+		}
+		return msg
 	}
 }
 
-// createRecord creates a new record in the database
-func (m *mainModel) createRecord(data interface{}) tea.Cmd {
+// createRecord adds a new record to the database
+func (m *mainModel) createRecord(r record.Record) tea.Cmd {
 	return func() tea.Msg {
-		// TODO: Implement when CRUD UI is designed
-		// record, err := m.db.CreateRecord(data)
-		// return recordCreatedMsg{record: record, err: err}
-		return recordCreatedMsg{record: data, err: nil}
+		entries, err := m.db.PutRecord(r)
+		return recordCreatedMsg{recordID: r.ID(), change: changeSet{"create " + r.ID(), entries}, err: err}
 	}
 }
 
-// updateRecord updates an existing record
-func (m *mainModel) updateRecord(id string, data interface{}) tea.Cmd {
+// allocateRecord assigns the next free address of the current network
+func (m *mainModel) allocateRecord() tea.Cmd {
+	network := m.network.Name
+	return func() tea.Msg {
+		r, entries, err := m.db.Allocate(network, "", record.RoleNone)
+		return recordAllocatedMsg{recordID: r.ID(), change: changeSet{"allocate " + r.ID(), entries}, err: err}
+	}
+}
+
+// releaseRecord moves a record into quarantine
+func (m *mainModel) releaseRecord(id string) tea.Cmd {
+	network := m.network.Name
+	return func() tea.Msg {
+		_, entries, err := m.db.Release(network, id)
+		return recordReleasedMsg{recordID: id, change: changeSet{"release " + id, entries}, err: err}
+	}
+}
+
+// checkAlerts samples utilisation and raises alerts after a change, as the
+// CLI does after each of its own
+func (m *mainModel) checkAlerts() tea.Cmd {
+	return func() tea.Msg {
+		alerts, err := alert.Run(m.db, m.config)
+		return alertsCheckedMsg{alerts: alerts, err: err}
+	}
+}
+
+// loadNetworks lists every network for the command palette
+func (m *mainModel) loadNetworks() tea.Cmd {
+	return func() tea.Msg {
+		networks, err := m.db.ListNetworks()
+		if err != nil {
+			return errorMsg{context: "loading networks", err: err}
+		}
+		return networksLoadedMsg{networks: networks}
+	}
+}
+
+// exportRecords writes the records currently listed to a file named after
+// the network in the working directory
+func (m *mainModel) exportRecords(format string) tea.Cmd {
+	n, records := m.network, m.visibleRecords()
+	return func() tea.Msg {
+		path := fmt.Sprintf("%s-%s.%s", n.Name, time.Now().Format("20060102-150405"), format)
+		f, err := os.Create(path)
+		if err != nil {
+			return errorMsg{context: "export", err: err}
+		}
+		defer f.Close()
+
+		switch format {
+		case "json":
+			if records == nil {
+				records = []record.Record{}
+			}
+			enc := json.NewEncoder(f)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(records)
+		default:
+			err = record.WriteCSV(f, []record.Network{n}, records)
+		}
+		if err != nil {
+			return errorMsg{context: "export", err: err}
+		}
+		return statusMsg{severity: severitySuccess, text: fmt.Sprintf("Exported %d records to %s", len(records), path)}
+	}
+}
+
+// updateRecord saves the edited details of an existing record
+func (m *mainModel) updateRecord(r record.Record) tea.Cmd {
+	if r.Equal(m.currentRecord) {
+		return func() tea.Msg { return enterDetailViewMsg{recordID: r.ID()} }
+	}
 	return func() tea.Msg {
-		// TODO: Implement when CRUD UI is designed
-		// err := m.db.UpdateRecord(id, data)
-		// return recordUpdatedMsg{recordID: id, err: err}
-		return recordUpdatedMsg{recordID: id, err: nil}
+		entries, err := m.db.PutRecord(r)
+		return recordUpdatedMsg{recordID: r.ID(), change: changeSet{"edit " + r.ID(), entries}, err: err}
 	}
 }
 
 // deleteRecord deletes a record from the database
 func (m *mainModel) deleteRecord(id string) tea.Cmd {
+	network := m.network.Name
 	return func() tea.Msg {
-		// TODO: Implement when CRUD UI is designed
-		// err := m.db.DeleteRecord(id)
-		// return recordDeletedMsg{recordID: id, err: err}
-		return recordDeletedMsg{recordID: id, err: nil}
+		entries, err := m.db.DeleteRecord(network, id)
+		return recordDeletedMsg{recordID: id, change: changeSet{"delete " + id, entries}, err: err}
 	}
 }
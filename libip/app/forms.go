@@ -2,7 +2,10 @@ package app
 
 import (
 	"fmt"
+	"maps"
 	"net/netip"
+	"strings"
+	"time"
 
 	"github.com/bakedSpaceTime/binip/libip/record"
 	"github.com/bakedSpaceTime/binip/libip/styles"
@@ -25,10 +28,32 @@ func (m *mainModel) prefixSelectForm() *huh.Form {
 				Title("Select Network Prefix").
 				Options(options...).
 				Value(&m.formPrefix), // Bind to temporary form field
+			huh.NewSelect[string]().
+				Title("Subnet Template").
+				Description("Lay out role ranges and placeholder records").
+				Options(m.templateOptions()...).
+				Value(&m.formTemplate), // Bind to temporary form field
 		),
 	)
 }
 
+// templateOptions lists the stored subnet templates, led by a bare-prefix option
+func (m *mainModel) templateOptions() []huh.Option[string] {
+	options := []huh.Option[string]{huh.NewOption("None (bare prefix)", "")}
+	templates, err := m.db.ListTemplates()
+	if err != nil {
+		return options
+	}
+	for _, t := range templates {
+		label := t.Name
+		if t.Description != "" {
+			label = fmt.Sprintf("%s - %s", t.Name, t.Description)
+		}
+		options = append(options, huh.NewOption(label, t.Name))
+	}
+	return options
+}
+
 // customPrefixForm allows user to enter a custom prefix
 func (m *mainModel) customPrefixForm() *huh.Form {
 	m.formPrefix = "" // Clear previous value
@@ -55,6 +80,9 @@ func (m *mainModel) confirmPrefixForm(prefix string) *huh.Form {
 	t.Rows(
 		[]string{"Prefix you Entered", prefix},
 	)
+	if m.formTemplate != "" {
+		t.Row("Template", m.formTemplate)
+	}
 	fc := styles.AccentStyle.Render(">")
 	fp := styles.InfoStyle.Render(netip.MustParsePrefix(prefix).Masked().String())
 	return huh.NewForm(
@@ -68,36 +96,192 @@ func (m *mainModel) confirmPrefixForm(prefix string) *huh.Form {
 	)
 }
 
-// === CRUD Forms (Templates for future implementation) ===
+// === CRUD Forms ===
 
-// createRecordForm creates a form for creating a new record
+// createRecordForm creates a form for adding a record to the current network
 func (m *mainModel) createRecordForm() *huh.Form {
-	// TODO: Implement when CRUD UI is designed
+	m.formRecord = record.Record{Network: m.network.Name, State: record.Reserved}
+	m.formAddr = ""
+	states := make([]huh.Option[record.State], len(record.States))
+	for i, st := range record.States {
+		states[i] = huh.NewOption(st.String(), st)
+	}
+	fields := []huh.Field{
+		huh.NewInput().
+			Title("Address").
+			Description(fmt.Sprintf("Within %s", m.network.Prefix)).
+			Value(&m.formAddr).
+			Validate(func(s string) error {
+				a, err := netip.ParseAddr(s)
+				if err != nil {
+					return err
+				}
+				if !m.network.Contains(a) {
+					return fmt.Errorf("%s is outside %s", a, m.network.Prefix)
+				}
+				return nil
+			}),
+		huh.NewSelect[record.State]().
+			Title("State").
+			Inline(true).
+			Options(states...).
+			Value(&m.formRecord.State),
+	}
 	return huh.NewForm(
-		huh.NewGroup(
-			huh.NewNote().
-				Title("Create Record").
-				Description("Form to be designed"),
-		),
+		huh.NewGroup(append(fields, m.recordInputs()...)...).Title("New Record"),
 	)
 }
 
-// editRecordForm creates a form for editing an existing record
+// editRecordForm creates a form for editing the details of the record shown
+// in the detail view
 func (m *mainModel) editRecordForm(recordID string) *huh.Form {
-	// TODO: Implement when CRUD UI is designed
-	// Load the record and populate form fields
+	m.formRecord = m.currentRecord
 	return huh.NewForm(
-		huh.NewGroup(
+		huh.NewGroup(append([]huh.Field{
 			huh.NewNote().
 				Title("Edit Record").
-				Description("Form to be designed for record: " + recordID),
-		),
+				Description(recordID),
+		}, m.recordInputs()...)...),
 	)
 }
 
+// recordInputs returns the inputs for the editable details of m.formRecord,
+// including its tags and an input per custom field of the network
+func (m *mainModel) recordInputs() []huh.Field {
+	m.formTags = strings.Join(m.formRecord.Tags, ", ")
+	m.formFields = make([]string, len(m.network.Fields))
+	for i, f := range m.network.Fields {
+		m.formFields[i] = m.formRecord.Fields[f.Name]
+	}
+
+	inputs := []huh.Field{
+		huh.NewInput().
+			Title("Hostname").
+			DescriptionFunc(m.hostnameHint, &m.formAddr).
+			PlaceholderFunc(m.suggestHostname, &m.formAddr).
+			SuggestionsFunc(func() []string {
+				if h := m.suggestHostname(); h != "" {
+					return []string{h}
+				}
+				return nil
+			}, &m.formAddr).
+			Value(&m.formRecord.Hostname).
+			Validate(m.validateHostname),
+		huh.NewInput().
+			Title("MAC").
+			Value(&m.formRecord.MAC),
+		huh.NewInput().
+			Title("Owner").
+			Value(&m.formRecord.Owner),
+		huh.NewInput().
+			Title("Description").
+			Value(&m.formRecord.Description),
+		huh.NewInput().
+			Title("Tags").
+			Description("Comma or space separated").
+			Value(&m.formTags).
+			Validate(func(s string) error {
+				return m.network.ValidateRecord(record.Record{Tags: record.ParseTags(s)})
+			}),
+	}
+	for i, f := range m.network.Fields {
+		if f.Type == record.FieldEnum {
+			options := []huh.Option[string]{huh.NewOption("(none)", "")}
+			for _, v := range f.Values {
+				options = append(options, huh.NewOption(v, v))
+			}
+			inputs = append(inputs, huh.NewSelect[string]().
+				Title(f.Name).
+				Inline(true).
+				Options(options...).
+				Value(&m.formFields[i]))
+			continue
+		}
+		inputs = append(inputs, huh.NewInput().
+			Title(f.Name).
+			Description(f.Type.String()).
+			Value(&m.formFields[i]).
+			Validate(func(s string) error {
+				if s == "" {
+					return nil
+				}
+				return f.Check(s)
+			}))
+	}
+	return inputs
+}
+
+// formAddress returns the address of the record being created or edited
+func (m *mainModel) formAddress() (netip.Addr, bool) {
+	if m.operationalMode != createView {
+		return m.formRecord.Addr, true
+	}
+	a, err := netip.ParseAddr(strings.TrimSpace(m.formAddr))
+	return a, err == nil && m.network.Contains(a)
+}
+
+// suggestHostname returns the next unused hostname the network's naming
+// template gives the record's address, if it has one
+func (m *mainModel) suggestHostname() string {
+	addr, ok := m.formAddress()
+	if !ok || m.network.Naming.Template == "" {
+		return ""
+	}
+	used := make(map[string]bool, len(m.records))
+	for _, r := range m.records {
+		used[strings.ToLower(r.Hostname)] = true
+	}
+	h, _ := m.network.SuggestHostname(addr, func(h string) bool { return used[h] })
+	return h
+}
+
+// hostnameHint describes the naming policy and the suggested hostname
+func (m *mainModel) hostnameHint() string {
+	if m.network.Naming.IsZero() {
+		return ""
+	}
+	if h := m.suggestHostname(); h != "" {
+		return fmt.Sprintf("Suggested %s; type its first letter and press ctrl+e to complete it", h)
+	}
+	return "Must follow " + m.network.Naming.String()
+}
+
+// validateHostname checks a hostname against the naming policy and the
+// hostnames already held in the network. A hostname kept unchanged from
+// before the policy is allowed.
+func (m *mainModel) validateHostname(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	addr, _ := m.formAddress()
+	if r, err := m.db.LookupHostname(m.network.Name, s); err == nil && r.Addr != addr {
+		return fmt.Errorf("hostname %s is already used by %s", s, r.ID())
+	}
+	if m.operationalMode == editView && strings.EqualFold(s, m.currentRecord.Hostname) {
+		return nil
+	}
+	return m.network.CheckHostname(s)
+}
+
+// formResult returns m.formRecord with the tag and custom field inputs
+// applied
+func (m *mainModel) formResult() record.Record {
+	r := m.formRecord
+	r.Tags = record.ParseTags(m.formTags)
+	r.Fields = maps.Clone(r.Fields)
+	for i, f := range m.network.Fields {
+		r.SetField(f.Name, strings.TrimSpace(m.formFields[i]))
+	}
+	if len(r.Fields) == 0 {
+		r.Fields = nil
+	}
+	return r
+}
+
 // deleteConfirmForm creates a confirmation form for deleting a record
 func (m *mainModel) deleteConfirmForm(recordID string) *huh.Form {
-	// TODO: Implement when CRUD UI is designed
+	m.formConfirmed = false
 	return huh.NewForm(
 		huh.NewGroup(
 			huh.NewConfirm().
@@ -108,3 +292,21 @@ func (m *mainModel) deleteConfirmForm(recordID string) *huh.Form {
 		),
 	)
 }
+
+// timeTravelForm asks for the moment to view the address plan as of
+func (m *mainModel) timeTravelForm() *huh.Form {
+	m.formAt = ""
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("View the address plan as of").
+				Placeholder("2006-01-02 15:04, 2006-01-02 or 72h").
+				Description("Date, date and time, RFC 3339 or a duration ago").
+				Value(&m.formAt).
+				Validate(func(s string) error {
+					_, err := record.ParseTime(s, time.Now())
+					return err
+				}),
+		),
+	)
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"fmt"
 
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/davecgh/go-spew/spew"
 )
@@ -58,6 +60,10 @@ func (m *mainModel) handleOnboardingMessage(msg tea.Msg) tea.Cmd {
 		// User rejected, go back to selection
 		return m.transitionToOnboardingState(selectingPrefix, "")
 
+	case revisionTickMsg:
+		// Nothing to refresh until onboarding is done
+		return pollRevision()
+
 	case dbOperationCompleteMsg:
 		if m.config.Debug {
 			spew.Fdump(m.config.DebugWriter, msg, "db operation complete")
@@ -65,12 +71,12 @@ func (m *mainModel) handleOnboardingMessage(msg tea.Msg) tea.Cmd {
 
 		if msg.operation == "save" && msg.success {
 			// Successfully saved network prefix, transition to operational
-			return m.transitionToState(operational)
+			return tea.Batch(m.checkAlerts(), m.transitionToState(operational))
 		}
 		// Save failed, show error and stay in onboarding
-		m.msg = fmt.Sprintf("Error saving to database: %v", msg.err)
+		status := m.setStatus(severityError, fmt.Sprintf("Error saving to database: %v", msg.err))
 		// Go back to prefix selection to try again
-		return m.transitionToOnboardingState(selectingPrefix, "")
+		return tea.Batch(status, m.transitionToOnboardingState(selectingPrefix, ""))
 	}
 
 	return nil
@@ -85,11 +91,31 @@ func (m *mainModel) handleOperationalMessage(msg tea.Msg) tea.Cmd {
 		}
 		return m.transitionToOperationalMode(listView)
 
+	case enterMapViewMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "entering map view")
+		}
+		return m.transitionToOperationalMode(mapView)
+
+	case enterDashboardViewMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "entering dashboard view")
+		}
+		return m.transitionToOperationalMode(dashboardView)
+
+	case utilisationLoadedMsg:
+		m.usages = msg.usages
+		return nil
+
 	case enterDetailViewMsg:
 		if m.config.Debug {
 			spew.Fdump(m.config.DebugWriter, msg, "entering detail view")
 		}
+		if m.operationalMode == listView || m.operationalMode == mapView {
+			m.previousMode = m.operationalMode
+		}
 		m.currentRecordID = msg.recordID
+		m.visit(msg.recordID)
 		return m.transitionToOperationalMode(detailView)
 
 	case enterCreateViewMsg:
@@ -105,19 +131,69 @@ func (m *mainModel) handleOperationalMessage(msg tea.Msg) tea.Cmd {
 		m.currentRecordID = msg.recordID
 		return m.transitionToOperationalMode(editView)
 
+	case enterDeleteConfirmViewMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "entering delete confirm view")
+		}
+		m.currentRecordID = msg.recordID
+		return m.transitionToOperationalMode(deleteConfirmView)
+
+	case changeRevertedMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "change reverted")
+		}
+		return m.handleChangeReverted(msg)
+
 	case recordCreatedMsg:
 		if m.config.Debug {
 			spew.Fdump(m.config.DebugWriter, msg, "record created")
 		}
 
 		if msg.err == nil {
-			// Success: show message and go back to list view
-			m.msg = "Record created successfully"
-			return func() tea.Msg { return enterListViewMsg{} }
+			// Success: show message and open the new record
+			check := m.recordChange(msg.change)
+			return tea.Batch(
+				check,
+				m.setStatus(severitySuccess, "Record created successfully"),
+				func() tea.Msg { return enterDetailViewMsg{recordID: msg.recordID} },
+			)
 		}
 		// Handle error
-		m.msg = fmt.Sprintf("Error creating record: %v", msg.err)
-		return nil
+		return m.setStatus(severityError, fmt.Sprintf("Error creating record: %v", msg.err))
+
+	case recordAllocatedMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "record allocated")
+		}
+
+		if msg.err == nil {
+			check := m.recordChange(msg.change)
+			return tea.Batch(
+				check,
+				m.setStatus(severitySuccess, fmt.Sprintf("Allocated %s", msg.recordID)),
+				func() tea.Msg { return enterDetailViewMsg{recordID: msg.recordID} },
+			)
+		}
+		return m.setStatus(severityError, fmt.Sprintf("Error allocating: %v", msg.err))
+
+	case recordsAllocatedMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "records allocated")
+		}
+
+		if msg.err == nil {
+			check := m.recordChange(msg.change)
+			first, last := msg.records[0], msg.records[len(msg.records)-1]
+			return tea.Batch(
+				check,
+				m.setStatus(severitySuccess, fmt.Sprintf("Allocated %d addresses, %s to %s", len(msg.records), first.ID(), last.ID())),
+				m.transitionToOperationalMode(listView),
+			)
+		}
+		return tea.Batch(
+			m.setStatus(severityError, fmt.Sprintf("Error allocating: %v", msg.err)),
+			m.transitionToOperationalMode(listView),
+		)
 
 	case recordUpdatedMsg:
 		if m.config.Debug {
@@ -126,14 +202,57 @@ func (m *mainModel) handleOperationalMessage(msg tea.Msg) tea.Cmd {
 
 		if msg.err == nil {
 			// Success: show message and go back to detail view
-			m.msg = "Record updated successfully"
-			return func() tea.Msg {
-				return enterDetailViewMsg{recordID: msg.recordID}
-			}
+			check := m.recordChange(msg.change)
+			return tea.Batch(
+				check,
+				m.setStatus(severitySuccess, "Record updated successfully"),
+				func() tea.Msg { return enterDetailViewMsg{recordID: msg.recordID} },
+			)
 		}
 		// Handle error
-		m.msg = fmt.Sprintf("Error updating record: %v", msg.err)
-		return nil
+		return m.setStatus(severityError, fmt.Sprintf("Error updating record: %v", msg.err))
+
+	case recordReleasedMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "record released")
+		}
+
+		if msg.err == nil {
+			check := m.recordChange(msg.change)
+			return tea.Batch(
+				check,
+				m.setStatus(severitySuccess, fmt.Sprintf("Released %s into quarantine", msg.recordID)),
+				m.transitionToOperationalMode(m.refreshMode()),
+			)
+		}
+		return m.setStatus(severityError, fmt.Sprintf("Error releasing: %v", msg.err))
+
+	case bulkProgressMsg:
+		if m.bulk != nil {
+			m.bulk.done = msg.done
+		}
+		return waitBulkProgress(msg.progress)
+
+	case bulkAppliedMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "bulk change applied")
+		}
+
+		change := m.bulk.change
+		m.bulk = nil
+		if msg.err == nil {
+			check := m.recordChange(msg.change)
+			m.marked = nil
+			return tea.Batch(
+				check,
+				m.setStatus(severitySuccess, fmt.Sprintf("Applied %s to %d records", change, msg.count)),
+				m.transitionToOperationalMode(listView),
+			)
+		}
+		return tea.Batch(
+			m.setStatus(severityError, fmt.Sprintf("Bulk change rejected, nothing was changed: %v", msg.err)),
+			m.transitionToOperationalMode(listView),
+		)
 
 	case recordDeletedMsg:
 		if m.config.Debug {
@@ -142,18 +261,362 @@ func (m *mainModel) handleOperationalMessage(msg tea.Msg) tea.Cmd {
 
 		if msg.err == nil {
 			// Success: show message and go back to list view
-			m.msg = "Record deleted successfully"
-			return func() tea.Msg { return enterListViewMsg{} }
+			check := m.recordChange(msg.change)
+			m.currentRecordID = ""
+			return tea.Batch(
+				check,
+				m.setStatus(severitySuccess, "Record deleted successfully"),
+				func() tea.Msg { return enterListViewMsg{} },
+			)
 		}
 		// Handle error
-		m.msg = fmt.Sprintf("Error deleting record: %v", msg.err)
+		return m.setStatus(severityError, fmt.Sprintf("Error deleting record: %v", msg.err))
+
+	case networkLoadedMsg:
+		m.network = msg.network
+		m.searches = msg.searches
+		m.revision = msg.revision
+		m.loaded = true
+		return m.restoreSession(msg.session)
+
+	case recordsLoadedMsg:
+		if msg.refresh {
+			return m.applyRefresh(msg)
+		}
+		m.network = msg.network
+		m.records = msg.records
+		m.clampCursor()
+		return m.selectPending()
+
+	case recordLoadedMsg:
+		m.currentRecord = msg.record
+		m.forecast = msg.forecast
+		m.history = msg.history
 		return nil
 
+	case tea.KeyMsg:
+		return m.handleOperationalKey(msg)
+
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+
+	case revisionTickMsg:
+		return m.checkRevision()
+
+	case revisionMsg:
+		if msg.err != nil {
+			// A busy database is tried again on the next tick
+			return pollRevision()
+		}
+		if !m.loaded {
+			// The network never loaded, perhaps because the database was
+			// busy; try again
+			return tea.Batch(m.loadOperationalData(), pollRevision())
+		}
+		if msg.revision == m.revision {
+			return pollRevision()
+		}
+		refresh := m.refresh()
+		if refresh == nil {
+			// Forms and time travel catch up once they are left
+			return pollRevision()
+		}
+		m.revision = msg.revision
+		return tea.Batch(refresh, pollRevision())
+
+	case recordRemovedMsg:
+		if m.operationalMode != detailView || m.currentRecordID != msg.recordID {
+			return nil
+		}
+		m.currentRecordID = ""
+		return tea.Batch(
+			m.setStatus(severityWarn, fmt.Sprintf("%s was removed elsewhere", msg.recordID)),
+			m.transitionToOperationalMode(listView),
+		)
+
+	case changedExpiredMsg:
+		if msg.seq == m.changedSeq {
+			m.changed = nil
+		}
+		return nil
+
+	case alertsCheckedMsg:
+		if msg.err != nil {
+			return m.setStatus(severityWarn, fmt.Sprintf("Alert check failed: %v", msg.err))
+		}
+		if len(msg.alerts) == 0 {
+			return nil
+		}
+		text := msg.alerts[0].Message
+		if len(msg.alerts) > 1 {
+			text += fmt.Sprintf(" (and %d more alerts)", len(msg.alerts)-1)
+		}
+		return m.setStatus(severityWarn, text)
+
 	case statusMsg:
 		// Just display the status message
-		m.msg = string(msg)
+		return m.setStatus(msg.severity, msg.text)
+
+	case actionMsg:
+		return m.runAction(msg.action)
+
+	case networksLoadedMsg:
+		if m.palette != nil {
+			m.palette.networks = msg.networks
+		}
+		return nil
+
+	case switchNetworkMsg:
+		if m.config.Debug {
+			spew.Fdump(m.config.DebugWriter, msg, "switching network")
+		}
+		m.resetNetwork(msg.network)
+		return m.transitionToOperationalMode(listView)
+
+	case jumpMsg:
+		return m.jumpTo(msg.to)
+
+	case exportMsg:
+		return m.exportRecords(msg.format)
+
+	case themeMsg:
+		if err := styles.Load(msg.theme); err != nil {
+			return m.setStatus(severityError, err.Error())
+		}
+		return m.setStatus(severitySuccess, "Theme "+styles.Active().Name)
+	}
+
+	return nil
+}
+
+// handleOperationalKey handles key presses for the non-form operational modes
+func (m *mainModel) handleOperationalKey(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case m.palette != nil:
+		return m.handlePaletteKey(msg)
+	case m.search.Focused():
+		return m.handleSearchKey(msg)
+	case m.editing():
+		return nil
+	}
+	if action, ok := m.keyAction(msg); ok {
+		return m.runAction(action)
+	}
+	return nil
+}
+
+// keyAction finds the action a key is bound to in the current mode
+func (m *mainModel) keyAction(msg tea.KeyMsg) (string, bool) {
+	actions := m.keys.actions()
+	for _, group := range modeActions[m.operationalMode] {
+		for _, name := range group {
+			if key.Matches(msg, *actions[name]) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// mutatingActions change the address plan and are refused while time
+// travelling
+var mutatingActions = map[string]bool{
+	"undo": true, "redo": true, "create": true, "allocate": true,
+	"edit": true, "delete": true, "release": true, "bulk": true,
+	"allocate_many": true,
+}
+
+// runAction performs an action of the current mode, whether its key was
+// pressed or it was chosen from the command palette
+func (m *mainModel) runAction(action string) tea.Cmd {
+	switch {
+	case action == "help":
+		m.help.ShowAll = !m.help.ShowAll
 		return nil
+	case action == "quit":
+		save := m.saveSession()
+		m.state = quitting
+		return tea.Sequence(save, tea.Quit)
+	case action == "palette":
+		return m.openPalette()
+	case action == "jump":
+		return m.openJumps()
+	case m.at != nil && mutatingActions[action]:
+		return notify(severityWarn, fmt.Sprintf("Read-only while viewing %s; press %s to return to now", m.at.Format("2006-01-02 15:04"), m.keys.TimeTravel.Help().Key))
+	case action == "time_travel" && (m.operationalMode == listView || m.operationalMode == mapView):
+		if m.at != nil {
+			m.at = nil
+			m.clearStatus()
+			return m.transitionToOperationalMode(m.operationalMode)
+		}
+		m.previousMode = m.operationalMode
+		return m.transitionToOperationalMode(timeTravelView)
+	case action == "undo":
+		return m.undo()
+	case action == "redo":
+		return m.redo()
+	}
+
+	switch m.operationalMode {
+	case listView:
+		switch action {
+		case "up":
+			m.cursor--
+			m.clampCursor()
+		case "down":
+			m.cursor++
+			m.clampCursor()
+		case "page_up":
+			m.cursor -= m.pageSize()
+			m.clampCursor()
+		case "page_down":
+			m.cursor += m.pageSize()
+			m.clampCursor()
+		case "top":
+			m.cursor = 0
+			m.clampCursor()
+		case "bottom":
+			m.cursor = len(m.visibleRecords()) - 1
+			m.clampCursor()
+		case "allocate":
+			return m.allocateRecord()
+		case "allocate_many":
+			return m.transitionToOperationalMode(allocateManyView)
+		case "copy", "copy_line", "copy_cidr", "copy_json":
+			if r, ok := m.selectedRecord(); ok {
+				return m.copyRecord(action, r)
+			}
+		case "release":
+			if r, ok := m.selectedRecord(); ok {
+				return m.releaseRecord(r.ID())
+			}
+		case "bookmark":
+			if r, ok := m.selectedRecord(); ok {
+				return m.toggleBookmark(r.ID())
+			}
+		case "mark":
+			m.toggleMark()
+			m.cursor++
+			m.clampCursor()
+		case "mark_up":
+			m.extendMarks(-1)
+		case "mark_down":
+			m.extendMarks(1)
+		case "mark_all":
+			m.markAll()
+		case "bulk":
+			if m.bulk != nil {
+				return notify(severityWarn, "A bulk change is still being applied")
+			}
+			if len(m.bulkTargets()) > 0 {
+				return m.transitionToOperationalMode(bulkView)
+			}
+		case "filter":
+			m.cycleStateFilter()
+		case "search":
+			return m.search.Focus()
+		case "create":
+			m.previousMode = listView
+			return func() tea.Msg { return enterCreateViewMsg{} }
+		case "columns":
+			m.showColumns = !m.showColumns
+		case "saved_search":
+			m.cycleSavedSearch()
+		case "map":
+			return func() tea.Msg { return enterMapViewMsg{} }
+		case "dashboard":
+			return func() tea.Msg { return enterDashboardViewMsg{} }
+		case "select":
+			if r, ok := m.selectedRecord(); ok {
+				return func() tea.Msg { return enterDetailViewMsg{recordID: r.ID()} }
+			}
+		}
+
+	case mapView:
+		return m.runMapAction(action)
+
+	case dashboardView:
+		if action == "back" || action == "dashboard" {
+			return func() tea.Msg { return enterListViewMsg{} }
+		}
+
+	case detailView:
+		switch action {
+		case "edit":
+			return func() tea.Msg { return enterEditViewMsg{recordID: m.currentRecordID} }
+		case "delete":
+			return func() tea.Msg { return enterDeleteConfirmViewMsg{recordID: m.currentRecordID} }
+		case "copy", "copy_line", "copy_cidr", "copy_json":
+			return m.copyRecord(action, m.currentRecord)
+		case "release":
+			return m.releaseRecord(m.currentRecordID)
+		case "bookmark":
+			return m.toggleBookmark(m.currentRecordID)
+		case "back":
+			if m.previousMode == mapView {
+				return func() tea.Msg { return enterMapViewMsg{} }
+			}
+			return func() tea.Msg { return enterListViewMsg{} }
+		}
 	}
+	return nil
+}
 
+// runMapAction moves around the address map and drills into blocks
+func (m *mainModel) runMapAction(action string) tea.Cmd {
+	switch action {
+	case "up":
+		m.moveMapCursor(0, -1)
+	case "down":
+		m.moveMapCursor(0, 1)
+	case "left":
+		m.moveMapCursor(-1, 0)
+	case "right":
+		m.moveMapCursor(1, 0)
+	case "colour":
+		m.mapColour = (m.mapColour + 1) % 2
+	case "copy", "copy_line", "copy_cidr", "copy_json":
+		cells := m.mapCells(m.mapLayout())
+		if m.mapCursor >= len(cells) {
+			return nil
+		}
+		switch c := cells[m.mapCursor]; {
+		case c.record != nil:
+			return m.copyRecord(action, *c.record)
+		case !c.prefix.IsSingleIP():
+			return copyText("block", c.prefix.String())
+		case action == "copy_cidr":
+			return copyText("address", recordCIDR(m.network, c.prefix.Addr()))
+		case action == "copy_line" || action == "copy_json":
+			return notify(severityInfo, fmt.Sprintf("No record for %s", c.prefix.Addr()))
+		default:
+			return copyText("address", c.prefix.Addr().String())
+		}
+	case "map":
+		return func() tea.Msg { return enterListViewMsg{} }
+	case "back":
+		if len(m.mapStack) == 0 {
+			return func() tea.Msg { return enterListViewMsg{} }
+		}
+		m.mapStack = m.mapStack[:len(m.mapStack)-1]
+		m.mapCursor = 0
+	case "select":
+		g := m.mapLayout()
+		cells := m.mapCells(g)
+		if m.mapCursor >= len(cells) {
+			return nil
+		}
+		c := cells[m.mapCursor]
+		if g.cellBits > 0 {
+			m.mapStack = append(m.mapStack, c.prefix)
+			m.mapCursor = 0
+			return nil
+		}
+		if c.record == nil {
+			return notify(severityInfo, fmt.Sprintf("No record for %s", c.prefix.Addr()))
+		}
+		id := c.record.ID()
+		return func() tea.Msg { return enterDetailViewMsg{recordID: id} }
+	}
 	return nil
 }
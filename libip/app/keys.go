@@ -1,39 +1,377 @@
 package app
 
 import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
 	"github.com/charmbracelet/bubbles/key"
 )
 
-// keyMap defines a set of keybindings. To work for help it must satisfy
-// key.Map. It could also very easily be a map[string]key.Binding.
+// keyMap defines a set of keybindings. Which of them apply, and which are
+// shown in help, depends on the current mode; see modeActions.
 type keyMap struct {
-	Help key.Binding
-	Quit key.Binding
+	Up           key.Binding
+	Down         key.Binding
+	Left         key.Binding
+	Right        key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	Top          key.Binding
+	Bottom       key.Binding
+	Select       key.Binding
+	Back         key.Binding
+	Apply        key.Binding
+	Cancel       key.Binding
+	Filter       key.Binding
+	Search       key.Binding
+	SavedSearch  key.Binding
+	Columns      key.Binding
+	Map          key.Binding
+	Colour       key.Binding
+	Dashboard    key.Binding
+	Create       key.Binding
+	Allocate     key.Binding
+	AllocateMany key.Binding
+	Edit         key.Binding
+	Delete       key.Binding
+	Release      key.Binding
+	Mark         key.Binding
+	MarkUp       key.Binding
+	MarkDown     key.Binding
+	MarkAll      key.Binding
+	Bulk         key.Binding
+	Copy         key.Binding
+	CopyLine     key.Binding
+	CopyCIDR     key.Binding
+	CopyJSON     key.Binding
+	Undo         key.Binding
+	Redo         key.Binding
+	TimeTravel   key.Binding
+	Bookmark     key.Binding
+	Jump         key.Binding
+	Palette      key.Binding
+	Help         key.Binding
+	Quit         key.Binding
+}
+
+// actions maps the names used in the config file to the bindings they
+// override
+func (k *keyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":            &k.Up,
+		"down":          &k.Down,
+		"left":          &k.Left,
+		"right":         &k.Right,
+		"page_up":       &k.PageUp,
+		"page_down":     &k.PageDown,
+		"top":           &k.Top,
+		"bottom":        &k.Bottom,
+		"select":        &k.Select,
+		"back":          &k.Back,
+		"apply":         &k.Apply,
+		"cancel":        &k.Cancel,
+		"filter":        &k.Filter,
+		"search":        &k.Search,
+		"saved_search":  &k.SavedSearch,
+		"columns":       &k.Columns,
+		"map":           &k.Map,
+		"colour":        &k.Colour,
+		"dashboard":     &k.Dashboard,
+		"create":        &k.Create,
+		"allocate":      &k.Allocate,
+		"allocate_many": &k.AllocateMany,
+		"edit":          &k.Edit,
+		"delete":        &k.Delete,
+		"release":       &k.Release,
+		"mark":          &k.Mark,
+		"mark_up":       &k.MarkUp,
+		"mark_down":     &k.MarkDown,
+		"mark_all":      &k.MarkAll,
+		"bulk":          &k.Bulk,
+		"copy":          &k.Copy,
+		"copy_line":     &k.CopyLine,
+		"copy_cidr":     &k.CopyCIDR,
+		"copy_json":     &k.CopyJSON,
+		"undo":          &k.Undo,
+		"redo":          &k.Redo,
+		"time_travel":   &k.TimeTravel,
+		"bookmark":      &k.Bookmark,
+		"jump":          &k.Jump,
+		"palette":       &k.Palette,
+		"help":          &k.Help,
+		"quit":          &k.Quit,
+	}
+}
+
+// modeActions lists the actions active in each mode, grouped as they are
+// shown in the full help view. The first group is the short help.
+var modeActions = map[operationalMode][][]string{
+	listView: {
+		{"select", "search", "create", "allocate", "palette", "help", "quit"},
+		{"up", "down", "page_up", "page_down", "top", "bottom"},
+		{"filter", "saved_search", "columns", "release", "allocate_many"},
+		{"copy", "copy_line", "copy_cidr", "copy_json"},
+		{"mark", "mark_up", "mark_down", "mark_all", "bulk"},
+		{"undo", "redo", "time_travel", "map", "dashboard"},
+		{"bookmark", "jump"},
+	},
+	mapView: {
+		{"select", "back", "palette", "help", "quit"},
+		{"up", "down", "left", "right"},
+		{"colour", "map", "jump"},
+		{"copy", "copy_line", "copy_cidr", "copy_json"},
+		{"undo", "redo", "time_travel"},
+	},
+	detailView: {
+		{"edit", "delete", "back", "palette", "help", "quit"},
+		{"release", "undo", "redo"},
+		{"bookmark", "jump"},
+		{"copy", "copy_line", "copy_cidr", "copy_json"},
+	},
+	dashboardView: {
+		{"back", "palette", "help", "quit"},
+		{"dashboard", "undo", "redo", "jump"},
+	},
+	// huh renders the form's own keys; only leaving the form is ours
+	createView:        {{"cancel"}},
+	editView:          {{"cancel"}},
+	deleteConfirmView: {{"cancel"}},
+	timeTravelView:    {{"cancel"}},
+	bulkView:          {{"cancel"}},
+	allocateManyView:  {{"cancel"}},
+}
+
+// searchActions are active while the search bar or the command palette has
+// focus
+var searchActions = [][]string{{"apply", "cancel"}}
+
+// groups resolves grouped action names to their bindings
+func (k keyMap) groups(names [][]string) [][]key.Binding {
+	actions := k.actions()
+	out := make([][]key.Binding, len(names))
+	for i, group := range names {
+		for _, n := range group {
+			out[i] = append(out[i], *actions[n])
+		}
+	}
+	return out
 }
 
-// ShortHelp returns keybindings to be shown in the mini help view. It's part
-// of the key.Map interface.
-func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Help, k.Quit}
+// contextHelp adapts the key map to help.KeyMap for one mode
+type contextHelp struct {
+	groups [][]key.Binding
 }
 
-// FullHelp returns keybindings for the expanded help view. It's part of the
-// key.Map interface.
-func (k keyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{
-		// {k.Up, k.Down, k.Left, k.Right},
-		// {k.RotateFocusCw, k.RotateFocusCcw, k.RotateViewCw, k.RotateViewCcw},
-		{k.Help, k.Quit},
+func (h contextHelp) ShortHelp() []key.Binding {
+	return h.groups[0]
+}
+
+func (h contextHelp) FullHelp() [][]key.Binding {
+	return h.groups
+}
+
+// contextHelp returns the help for what the model is currently doing
+func (m *mainModel) contextHelp() contextHelp {
+	switch {
+	case m.state != operational:
+		return contextHelp{[][]key.Binding{{m.keys.Help, m.keys.Quit}}}
+	case m.palette != nil:
+		groups := m.keys.groups(searchActions)
+		groups[0] = append(groups[0], binding("choose", "up", "down"))
+		return contextHelp{groups}
+	case m.search.Focused():
+		return contextHelp{m.keys.groups(searchActions)}
+	}
+	return contextHelp{m.keys.groups(modeActions[m.operationalMode])}
+}
+
+// binding builds a key binding whose help shows its first key
+func binding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(helpKey(keys), desc))
+}
+
+// helpKey renders the keys of a binding for the help view
+func helpKey(keys []string) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		switch k {
+		case "up":
+			k = "↑"
+		case "down":
+			k = "↓"
+		case "left":
+			k = "←"
+		case "right":
+			k = "→"
+		case "backspace":
+			k = "⌫"
+		case " ":
+			k = "space"
+		}
+		names[i] = k
+	}
+	if len(names) > 2 {
+		names = names[:2]
 	}
+	return strings.Join(names, "/")
 }
 
-var keys = keyMap{
-	Help: key.NewBinding(
-		key.WithKeys("?"),
-		key.WithHelp("?", "toggle help"),
-	),
-	Quit: key.NewBinding(
-		key.WithKeys("q", "esc", "ctrl+c"),
-		key.WithHelp("q", "quit"),
-	),
+// defaultKeys is the default layout: arrows or hjkl to move and single
+// letters for actions
+func defaultKeys() keyMap {
+	return keyMap{
+		Up:           binding("up", "up", "k"),
+		Down:         binding("down", "down", "j"),
+		Left:         binding("left", "left", "h"),
+		Right:        binding("right", "right", "l"),
+		PageUp:       binding("page up", "pgup"),
+		PageDown:     binding("page down", "pgdown"),
+		Top:          binding("first", "home"),
+		Bottom:       binding("last", "end"),
+		Select:       binding("open", "enter"),
+		Back:         binding("back", "backspace"),
+		Apply:        binding("apply", "enter"),
+		Cancel:       binding("cancel", "esc"),
+		Filter:       binding("cycle state filter", "f"),
+		Search:       binding("search", "/"),
+		SavedSearch:  binding("cycle saved searches", "S"),
+		Columns:      binding("toggle tag and field columns", "F"),
+		Map:          binding("toggle address map", "m"),
+		Colour:       binding("cycle map colouring", "c"),
+		Dashboard:    binding("utilisation dashboard", "d"),
+		Create:       binding("new record", "n"),
+		Allocate:     binding("allocate next free", "a"),
+		AllocateMany: binding("allocate several", "A"),
+		Edit:         binding("edit record", "e"),
+		Delete:       binding("delete record", "x"),
+		Release:      binding("release to quarantine", "r"),
+		Mark:         binding("mark", " "),
+		MarkUp:       binding("mark upwards", "shift+up", "K"),
+		MarkDown:     binding("mark downwards", "shift+down", "J"),
+		MarkAll:      binding("mark all listed", "*"),
+		Bulk:         binding("bulk change marked", "B"),
+		Copy:         binding("copy address", "y"),
+		CopyLine:     binding("copy as line", "Y"),
+		CopyCIDR:     binding("copy address/prefix", "C"),
+		CopyJSON:     binding("copy as JSON", "ctrl+y"),
+		Undo:         binding("undo", "u"),
+		Redo:         binding("redo", "ctrl+r"),
+		TimeTravel:   binding("time travel", "t"),
+		Bookmark:     binding("toggle bookmark", "b"),
+		Jump:         binding("jump to bookmark or recent", "'"),
+		Palette:      binding("command palette", ":", "ctrl+p"),
+		Help:         binding("toggle help", "?"),
+		Quit:         binding("quit", "q", "esc", "ctrl+c"),
+	}
+}
+
+// vimKeys adds vim motions to the default layout; esc goes back rather than
+// quitting
+func vimKeys() keyMap {
+	k := defaultKeys()
+	k.PageUp = binding("page up", "ctrl+u", "pgup")
+	k.PageDown = binding("page down", "ctrl+d", "pgdown")
+	k.Top = binding("first", "g", "home")
+	k.Bottom = binding("last", "G", "end")
+	k.Back = binding("back", "esc", "backspace")
+	k.Quit = binding("quit", "q", "ctrl+c")
+	return k
+}
+
+// emacsKeys moves with control keys and keeps letters for actions
+func emacsKeys() keyMap {
+	k := defaultKeys()
+	k.Up = binding("up", "up", "ctrl+p")
+	k.Down = binding("down", "down", "ctrl+n")
+	k.Left = binding("left", "left", "ctrl+b")
+	k.Right = binding("right", "right", "ctrl+f")
+	k.PageUp = binding("page up", "alt+v", "pgup")
+	k.PageDown = binding("page down", "ctrl+v", "pgdown")
+	k.Top = binding("first", "alt+<", "home")
+	k.Bottom = binding("last", "alt+>", "end")
+	k.Back = binding("back", "ctrl+g", "backspace")
+	k.Search = binding("search", "ctrl+s", "/")
+	k.Undo = binding("undo", "ctrl+_", "u")
+	k.Redo = binding("redo", "alt+_", "ctrl+r")
+	k.Palette = binding("command palette", "alt+x", ":")
+	return k
+}
+
+// presets are the key layouts selectable with keys.preset in the config file
+var presets = map[string]func() keyMap{
+	"default": defaultKeys,
+	"vim":     vimKeys,
+	"emacs":   emacsKeys,
+}
+
+// newKeyMap builds the key map from a preset and the config file's
+// overrides, rejecting unknown actions and keys bound twice within a mode
+func newKeyMap(c config.Keys) (keyMap, error) {
+	name := c.Preset
+	if name == "" {
+		name = "default"
+	}
+	preset, ok := presets[name]
+	if !ok {
+		return keyMap{}, fmt.Errorf("keys: unknown preset %q (presets: default, emacs, vim)", name)
+	}
+	k := preset()
+
+	actions := k.actions()
+	for action, keys := range c.Bindings {
+		b, ok := actions[action]
+		if !ok {
+			names := make([]string, 0, len(actions))
+			for n := range actions {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return keyMap{}, fmt.Errorf("keys: unknown action %q (actions: %s)", action, strings.Join(names, ", "))
+		}
+		if len(keys) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(helpKey(keys), b.Help().Desc)
+	}
+	return k, k.validate()
+}
+
+// validate reports keys bound to more than one action within a mode
+func (k keyMap) validate() error {
+	actions := k.actions()
+	check := func(where string, groups [][]string) error {
+		bound := make(map[string]string)
+		for _, group := range groups {
+			for _, n := range group {
+				b := actions[n]
+				if !b.Enabled() {
+					continue
+				}
+				for _, key := range b.Keys() {
+					if other, ok := bound[key]; ok && other != n {
+						return fmt.Errorf("keys: %q is bound to both %s and %s in the %s", key, other, n, where)
+					}
+					bound[key] = n
+				}
+			}
+		}
+		return nil
+	}
+
+	modes := make([]operationalMode, 0, len(modeActions))
+	for mode := range modeActions {
+		modes = append(modes, mode)
+	}
+	slices.Sort(modes)
+	for _, mode := range modes {
+		if err := check(mode.String(), modeActions[mode]); err != nil {
+			return err
+		}
+	}
+	return check("search bar", searchActions)
 }
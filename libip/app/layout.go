@@ -0,0 +1,125 @@
+package app
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// splitMinWidth is the terminal width from which the list view shows the
+// selected record alongside the list
+const splitMinWidth = 120
+
+// splitPane reports whether the list and the selected record fit side by
+// side
+func (m *mainModel) splitPane() bool {
+	return m.width >= splitMinWidth
+}
+
+// paneWidths divides the terminal between the list and the record pane
+func (m *mainModel) paneWidths() (left, right int) {
+	left = m.width * 3 / 5
+	return left, m.width - left
+}
+
+// bodyHeight is the number of lines between the breadcrumb bar and the
+// status bar
+func (m *mainModel) bodyHeight() int {
+	height := m.height
+	if height == 0 {
+		height = 24
+	}
+	height -= max(lipgloss.Height(m.statusBar()), 1) + lipgloss.Height(m.footerView())
+	if m.state == operational {
+		height -= lipgloss.Height(m.breadcrumbBar())
+	}
+	return max(height, 1)
+}
+
+// breadcrumbBar shows where in the address plan the current view is
+func (m *mainModel) breadcrumbBar() string {
+	title := " " + strings.Join(m.breadcrumbs(), " › ") + " "
+	return styles.HeaderStyle.Render(title) + m.timeTravelLabel()
+}
+
+// breadcrumbs locate the current view as network › subnet › address, or the
+// blocks drilled into in the address map
+func (m *mainModel) breadcrumbs() []string {
+	network := m.network.Name
+	if m.network.Provider != record.ProviderNone {
+		network += fmt.Sprintf(" (%s)", m.network.Provider)
+	}
+
+	switch m.operationalMode {
+	case dashboardView:
+		return []string{"utilisation"}
+	case mapView:
+		crumbs := []string{network, m.network.Prefix.String()}
+		for _, p := range m.mapStack {
+			crumbs = append(crumbs, p.String())
+		}
+		return crumbs
+	case detailView, editView, deleteConfirmView:
+		if addr, err := netip.ParseAddr(m.currentRecordID); err == nil {
+			return m.addressCrumbs(network, addr)
+		}
+	case createView:
+		return []string{network, m.network.Prefix.String(), "new record"}
+	case timeTravelView:
+		return []string{network, "time travel"}
+	case bulkView:
+		return []string{network, m.network.Prefix.String(), "bulk change"}
+	case allocateManyView:
+		return []string{network, m.network.Prefix.String(), "allocate addresses"}
+	case listView:
+		if r, ok := m.selectedRecord(); ok && m.splitPane() {
+			return m.addressCrumbs(network, r.Addr)
+		}
+	}
+	return []string{network, m.network.Prefix.String()}
+}
+
+// addressCrumbs are the network, the range holding the address or else the
+// network prefix, and the address
+func (m *mainModel) addressCrumbs(network string, addr netip.Addr) []string {
+	subnet := m.network.Prefix.String()
+	if rg, ok := m.network.RangeOf(addr); ok {
+		subnet = rg.Name
+	}
+	return []string{network, subnet, addr.String()}
+}
+
+// splitListView places the list on the left and the selected record on the
+// right
+func (m *mainModel) splitListView() string {
+	left, right := m.paneWidths()
+	list := lipgloss.NewStyle().MaxWidth(left).Render(m.listView())
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.PlaceHorizontal(left, lipgloss.Left, list),
+		m.recordPane(right),
+	)
+}
+
+// recordPane shows the details of the selected record
+func (m *mainModel) recordPane(width int) string {
+	style := styles.PaneStyle.Width(width - 1)
+	r, ok := m.selectedRecord()
+	if !ok {
+		return style.Render(styles.InfoStyle.Render("No record selected"))
+	}
+	t := m.recordTable(r)
+	fitTable(t, width-2)
+	return style.Render(t.Render())
+}
+
+// fitTable narrows a table that is wider than width
+func fitTable(t *table.Table, width int) {
+	if width > 0 && lipgloss.Width(t.Render()) > width {
+		t.Width(width)
+	}
+}
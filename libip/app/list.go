@@ -0,0 +1,125 @@
+package app
+
+import (
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/query"
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+// visibleRecords returns the records that pass the active state filter and
+// search query. An invalid query filters nothing.
+func (m *mainModel) visibleRecords() []record.Record {
+	q, err := m.searchQuery()
+	saved := m.activeSearch()
+	if m.stateFilter == nil && saved == nil && (err != nil || q.empty()) {
+		return m.records
+	}
+	now := time.Now()
+	var out []record.Record
+	for _, r := range m.records {
+		if m.stateFilter != nil && r.State != *m.stateFilter {
+			continue
+		}
+		if saved != nil && !saved.query.Match(query.Subject{Network: m.network, Record: r}, now) {
+			continue
+		}
+		if err == nil {
+			if _, ok := q.match(m.network, r); !ok {
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// selectedRecord returns the record under the cursor
+func (m *mainModel) selectedRecord() (record.Record, bool) {
+	visible := m.visibleRecords()
+	if m.cursor < 0 || m.cursor >= len(visible) {
+		return record.Record{}, false
+	}
+	return visible[m.cursor], true
+}
+
+// clampCursor keeps the cursor within the filtered list
+func (m *mainModel) clampCursor() {
+	n := len(m.visibleRecords())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// resetNetwork starts browsing another network from the top, unfiltered
+func (m *mainModel) resetNetwork(name string) {
+	m.network = record.Network{Name: name}
+	m.cursor, m.mapStack, m.mapCursor = 0, nil, 0
+	m.stateFilter, m.marked = nil, nil
+}
+
+// cycleStateFilter steps the list filter through all, then each state in turn
+func (m *mainModel) cycleStateFilter() {
+	current := m.stateFilter
+	m.stateFilter = &record.States[0]
+	if current != nil {
+		m.stateFilter = nil
+		for i, st := range record.States[:len(record.States)-1] {
+			if st == *current {
+				m.stateFilter = &record.States[i+1]
+			}
+		}
+	}
+	m.cursor = 0
+}
+
+// stateCounts tallies the records of the current network by state
+func (m *mainModel) stateCounts() map[record.State]int {
+	counts := make(map[record.State]int)
+	for _, r := range m.records {
+		counts[r.State]++
+	}
+	return counts
+}
+
+// savedSearch is a saved record query parsed for the list view
+type savedSearch struct {
+	name  string
+	query *query.Query
+}
+
+// loadSavedSearches reads and parses the saved searches. Searches that no
+// longer parse are skipped.
+func (m *mainModel) loadSavedSearches() ([]savedSearch, error) {
+	stored, err := m.db.ListSearches()
+	if err != nil {
+		return nil, err
+	}
+	var searches []savedSearch
+	for _, s := range stored {
+		if q, err := query.Parse(s.Where, query.Records); err == nil {
+			searches = append(searches, savedSearch{name: s.Name, query: q})
+		}
+	}
+	return searches, nil
+}
+
+// activeSearch returns the applied saved search, if any
+func (m *mainModel) activeSearch() *savedSearch {
+	if m.savedSearch < 0 || m.savedSearch >= len(m.searches) {
+		return nil
+	}
+	return &m.searches[m.savedSearch]
+}
+
+// cycleSavedSearch steps through no saved search, then each in turn
+func (m *mainModel) cycleSavedSearch() {
+	m.savedSearch++
+	if m.savedSearch >= len(m.searches) {
+		m.savedSearch = -1
+	}
+	m.cursor = 0
+}
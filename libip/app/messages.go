@@ -1,5 +1,11 @@
 package app
 
+import (
+	"github.com/bakedSpaceTime/binip/libip/alert"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/report"
+)
+
 // === State Transition Messages ===
 
 // stateTransitionMsg indicates a transition between main states
@@ -40,9 +46,46 @@ type dbOperationCompleteMsg struct {
 
 // === Operational CRUD Messages ===
 
+// networkLoadedMsg is sent when the network to browse and the saved
+// searches have been read
+type networkLoadedMsg struct {
+	network  record.Network
+	searches []savedSearch
+	session  record.Session // Where the user left the previous session
+	revision uint64
+}
+
+// recordsLoadedMsg carries the current network and its records, as they
+// stood at the time travel moment if one is set
+type recordsLoadedMsg struct {
+	network record.Network
+	records []record.Record
+	refresh bool // Reloaded because another process changed the database
+}
+
+// recordLoadedMsg carries a single record for the detail view, along with
+// its audit history and the exhaustion forecast of its network when there is
+// enough history
+type recordLoadedMsg struct {
+	record   record.Record
+	forecast *report.Forecast
+	history  []record.AuditEntry
+}
+
 // enterListViewMsg requests transition to list view
 type enterListViewMsg struct{}
 
+// enterMapViewMsg requests transition to the address map
+type enterMapViewMsg struct{}
+
+// enterDashboardViewMsg requests transition to the utilisation dashboard
+type enterDashboardViewMsg struct{}
+
+// utilisationLoadedMsg carries the utilisation tree for the dashboard
+type utilisationLoadedMsg struct {
+	usages []report.Usage
+}
+
 // enterDetailViewMsg requests transition to detail view for a specific record
 type enterDetailViewMsg struct {
 	recordID string
@@ -56,24 +99,145 @@ type enterEditViewMsg struct {
 	recordID string
 }
 
+// enterDeleteConfirmViewMsg requests confirmation before deleting a record
+type enterDeleteConfirmViewMsg struct {
+	recordID string
+}
+
+// alertsCheckedMsg is sent once the alert check after a change has run
+type alertsCheckedMsg struct {
+	alerts []alert.Alert
+	err    error
+}
+
+// changeRevertedMsg is sent once a change has been undone or redone
+type changeRevertedMsg struct {
+	change changeSet
+	undo   bool
+	err    error
+}
+
 // recordCreatedMsg is sent when a record is created
 type recordCreatedMsg struct {
-	record interface{} // the created record (replace with your record type)
-	err    error
+	recordID string
+	change   changeSet
+	err      error
+}
+
+// recordAllocatedMsg is sent when the next free address has been allocated
+type recordAllocatedMsg struct {
+	recordID string
+	change   changeSet
+	err      error
 }
 
 // recordUpdatedMsg is sent when a record is updated
 type recordUpdatedMsg struct {
 	recordID string
+	change   changeSet
+	err      error
+}
+
+// recordReleasedMsg is sent when a record has been released into quarantine
+type recordReleasedMsg struct {
+	recordID string
+	change   changeSet
 	err      error
 }
 
 // recordDeletedMsg is sent when a record is deleted
 type recordDeletedMsg struct {
 	recordID string
+	change   changeSet
+	err      error
+}
+
+// === Command Palette Messages ===
+
+// actionMsg runs a key map action, as chosen from the command palette
+type actionMsg struct {
+	action string
+}
+
+// networksLoadedMsg carries every network for the command palette
+type networksLoadedMsg struct {
+	networks []record.Network
+}
+
+// switchNetworkMsg requests browsing another network
+type switchNetworkMsg struct {
+	network string
+}
+
+// jumpMsg requests opening a bookmarked or recent record
+type jumpMsg struct {
+	to record.Bookmark
+}
+
+// exportMsg requests writing the listed records to a file in the working
+// directory, as csv or json
+type exportMsg struct {
+	format string
+}
+
+// themeMsg requests switching the colour theme
+type themeMsg struct {
+	theme string
+}
+
+// recordsAllocatedMsg is sent when a bulk allocation has been made or
+// rejected
+type recordsAllocatedMsg struct {
+	records []record.Record
+	change  changeSet
+	err     error
+}
+
+// bulkProgressMsg reports how many records of a bulk change are done
+type bulkProgressMsg struct {
+	done     int
+	progress chan int
+}
+
+// bulkAppliedMsg is sent when a bulk change has been applied or rejected
+type bulkAppliedMsg struct {
+	count  int
+	change changeSet
+	err    error
+}
+
+// revisionTickMsg is sent when it is time to check the database revision
+type revisionTickMsg struct{}
+
+// revisionMsg carries the database revision
+type revisionMsg struct {
+	revision uint64
 	err      error
 }
 
+// recordRemovedMsg is sent when the record in the detail view has been
+// removed by another process
+type recordRemovedMsg struct {
+	recordID string
+}
+
+// changedExpiredMsg ends the highlight of changed rows
+type changedExpiredMsg struct {
+	seq int
+}
+
+// previewTickMsg is sent once the allocation form has been left unchanged
+// long enough to plan its preview
+type previewTickMsg struct {
+	seq int
+}
+
+// previewPlannedMsg carries the preview of the allocation form
+type previewPlannedMsg struct {
+	seq  int
+	text string
+}
+
 // === Error/Status Messages ===
 
 // errorMsg represents an error with context
@@ -83,4 +247,7 @@ type errorMsg struct {
 }
 
 // statusMsg represents a status message to display to user
-type statusMsg string
+type statusMsg struct {
+	severity severity
+	text     string
+}
@@ -3,11 +3,15 @@ package app
 import (
 	"fmt"
 	"net/netip"
+	"time"
 
 	"github.com/bakedSpaceTime/binip/libip/config"
 	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/report"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
@@ -25,21 +29,63 @@ type mainModel struct {
 	operationalMode operationalMode
 
 	// Temporary form binding fields (not persisted - data flows through messages)
-	formPrefix           string // Temporary: binds to prefix selection/input forms
-	formConfirmed        bool   // Temporary: binds to confirmation forms
-	prefixBeingConfirmed string // Temporary: holds prefix during confirmation flow
+	formPrefix           string        // Temporary: binds to prefix selection/input forms
+	formTemplate         string        // Temporary: binds to the subnet template selection
+	formConfirmed        bool          // Temporary: binds to confirmation forms
+	formRecord           record.Record // Temporary: binds to the record create/edit forms
+	formAddr             string        // Temporary: binds to the new record's address
+	formTags             string        // Temporary: binds to the record tags input
+	formFields           []string      // Temporary: binds to the custom field inputs, in network order
+	formAt               string        // Temporary: binds to the time travel form
+	formBulk             bulkForm      // Temporary: binds to the bulk change form
+	formAlloc            allocateForm  // Temporary: binds to the bulk allocation form
+	prefixBeingConfirmed string        // Temporary: holds prefix during confirmation flow
 
 	// Operational data
-	currentRecordID string        // Currently selected record ID
-	records         []interface{} // List of records (replace with your record type)
+	network         record.Network      // Network currently being browsed
+	currentRecordID string              // Currently selected record ID
+	currentRecord   record.Record       // Record shown in the detail view
+	forecast        *report.Forecast    // Exhaustion forecast of the current network
+	history         []record.AuditEntry // Audit entries of the current record
+	records         []record.Record     // Records of the current network
+	cursor          int                 // Selected row within the filtered list
+	marked          map[string]bool     // IDs of the records marked for a bulk change
+	bulk            *bulkRun            // Bulk change being applied, nil when idle
+	stateFilter     *record.State       // Only list records in this state when set
+	search          textinput.Model     // Search bar query narrowing the list
+	searches        []savedSearch       // Saved record queries
+	savedSearch     int                 // Index of the applied saved search, -1 for none
+	showColumns     bool                // List tags and custom fields as extra columns
+	previousMode    operationalMode     // Mode to return to when leaving the detail view
+	at              *time.Time          // Moment being viewed read-only, nil for now
+	pendingRecord   string              // Record to select once the list has loaded
+	pendingOpen     bool                // Open pendingRecord once it is selected
+	bookmarks       []record.Bookmark   // Bookmarked records of any network
+	recent          []record.Bookmark   // Recently opened records, newest first
+	revision        uint64              // Database revision last shown
+	changed         map[string]bool     // IDs of rows highlighted as changed elsewhere
+	changedSeq      int                 // Identifies the latest highlight so stale expiries are ignored
+
+	// Address map
+	mapStack  []netip.Prefix // Blocks drilled into, innermost last
+	mapCursor int            // Selected cell within the current grid
+	mapColour mapColouring   // What the map cells are coloured by
+
+	// Utilisation dashboard
+	usages []report.Usage
+
+	// Undo/redo of this session's changes, most recent last
+	undoStack []changeSet
+	redoStack []changeSet
 
 	// UI components
-	form   *huh.Form
-	keys   keyMap
-	help   help.Model
-	width  int
-	height int
-	msg    string // Status or error message to display
+	form    *huh.Form
+	palette *palette // Command palette, nil while closed
+	keys    keyMap
+	help    help.Model
+	width   int
+	height  int
+	status  statusLine // Status or error message to display
 
 	// Dependencies
 	config *config.Config
@@ -48,25 +94,45 @@ type mainModel struct {
 	// Internal
 	firstWindowMsg bool
 	lastKey        string
+	lastClick      mouseClick
+	loaded         bool // The network to browse has been loaded
 }
 
-func New(c *config.Config) *mainModel {
+// New builds the TUI model, failing if the configured key bindings are
+// invalid
+func New(c *config.Config) (*mainModel, error) {
+	keys, err := newKeyMap(c.Keys)
+	if err != nil {
+		return nil, err
+	}
 	m := mainModel{
 		state:           onboarding,
 		onboardingState: selectingPrefix,
 		keys:            keys,
 		help:            help.New(),
+		search:          newSearchInput(),
+		savedSearch:     -1,
 		config:          c,
-		db:              db.New(c),
+		db:              db.NewShared(c),
 		firstWindowMsg:  true,
 	}
 
+	// Skip onboarding once a network has been configured
+	if _, err := m.db.GetNetworkByName(record.DefaultNetwork); err == nil {
+		m.state = operational
+		return &m, nil
+	}
+
 	m.form = m.prefixSelectForm()
-	return &m
+	return &m, nil
 }
 
 func (m *mainModel) Init() tea.Cmd {
-	return m.form.Init()
+	// Polling runs for the whole session so that a failed load is retried
+	if m.state == operational {
+		return tea.Batch(m.loadOperationalData(), pollRevision())
+	}
+	return tea.Batch(m.form.Init(), pollRevision())
 }
 
 func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -75,7 +141,7 @@ func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Debug logging
 	if m.config.Debug {
 		spew.Fdump(m.config.DebugWriter, msg, "message received")
-		m.msg = spew.Sdump(msg)
+		m.status.text = spew.Sdump(msg)
 	}
 
 	// Global message handling (window size, keyboard shortcuts, errors)
@@ -87,6 +153,25 @@ func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		switch {
+		case m.search.Focused():
+			// The search bar takes every key while it has focus
+		case m.editing():
+			// Record forms take every key so that text can be typed; esc
+			// abandons the form
+			if key.Matches(msg, m.keys.Cancel) {
+				m.form = nil
+				switch m.operationalMode {
+				case timeTravelView:
+					return m, m.transitionToOperationalMode(m.previousMode)
+				case createView, bulkView, allocateManyView:
+					return m, m.transitionToOperationalMode(listView)
+				}
+				id := m.currentRecordID
+				return m, func() tea.Msg { return enterDetailViewMsg{recordID: id} }
+			}
+		case m.state == operational:
+			// Operational keys, help and quit included, are actions; see
+			// runAction
 		case key.Matches(msg, m.keys.Help):
 			m.help.ShowAll = !m.help.ShowAll
 		case key.Matches(msg, m.keys.Quit):
@@ -96,12 +181,29 @@ func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case errorMsg:
 		// Global error handling
-		m.msg = fmt.Sprintf("Error [%s]: %v", msg.context, msg.err)
 		if m.config.Debug {
 			spew.Fdump(m.config.DebugWriter, msg.err, "error occurred")
 		}
+		return m, m.setStatus(severityError, fmt.Sprintf("Error [%s]: %v", msg.context, msg.err))
+
+	case statusExpiredMsg:
+		if msg.seq == m.status.seq {
+			m.clearStatus()
+		}
 		return m, nil
 
+	case previewTickMsg:
+		if msg.seq == m.formAlloc.previewSeq {
+			return m, m.planPreview(msg.seq)
+		}
+		return m, nil
+
+	case previewPlannedMsg:
+		// Set before the form updates so that the preview note picks it up
+		if msg.seq == m.formAlloc.previewSeq {
+			m.formAlloc.preview = msg.text
+		}
+
 	case stateTransitionMsg:
 		// Log state transitions
 		if m.config.Debug {
@@ -120,6 +222,8 @@ func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Check for form completion and handle it
 		if m.form.State == huh.StateCompleted {
 			cmds = append(cmds, m.handleFormCompletion())
+		} else if m.state == operational && m.operationalMode == allocateManyView {
+			cmds = append(cmds, m.schedulePreview())
 		}
 	}
 
@@ -129,20 +233,36 @@ func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// editing reports whether a record form is taking input
+func (m *mainModel) editing() bool {
+	switch m.operationalMode {
+	case createView, editView, deleteConfirmView, timeTravelView, bulkView, allocateManyView:
+		return m.state == operational && m.form != nil && m.form.State == huh.StateNormal
+	}
+	return false
+}
+
+// View lays out the breadcrumb bar, the body sized to fill the terminal,
+// the status bar and the help footer
 func (m *mainModel) View() string {
-	var body string
+	var sections []string
 
 	switch m.state {
 	case onboarding:
-		body = m.onboardingView()
+		sections = append(sections, m.onboardingView())
 	case operational:
-		body = m.operationalView()
+		sections = append(sections, m.breadcrumbBar(), m.operationalView())
 	default:
-		body = ""
+		sections = append(sections, "")
 	}
 
-	footer := m.footerView()
-	return lipgloss.JoinVertical(lipgloss.Center, body, footer)
+	if m.height > 0 {
+		h := m.bodyHeight()
+		body := &sections[len(sections)-1]
+		*body = lipgloss.NewStyle().Height(h).MaxHeight(h).MaxWidth(m.width).Render(*body)
+	}
+	sections = append(sections, m.statusBar(), m.footerView())
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
 // handleFormCompletion handles form completion by returning appropriate messages
@@ -196,16 +316,34 @@ func (m *mainModel) handleOnboardingFormCompletion() tea.Cmd {
 func (m *mainModel) handleOperationalFormCompletion() tea.Cmd {
 	switch m.operationalMode {
 	case createView:
-		// TODO: Extract form data and create record
-		return func() tea.Msg {
-			return recordCreatedMsg{record: nil, err: nil}
-		}
+		r := m.formResult()
+		r.Addr = netip.MustParseAddr(m.formAddr)
+		return m.createRecord(r)
 
 	case editView:
-		// TODO: Extract form data and update record
-		return func() tea.Msg {
-			return recordUpdatedMsg{recordID: m.currentRecordID, err: nil}
+		return m.updateRecord(m.formResult())
+
+	case timeTravelView:
+		t, err := record.ParseTime(m.formAt, time.Now())
+		if err != nil {
+			return func() tea.Msg { return errorMsg{context: "time travel", err: err} }
+		}
+		m.at = &t
+		return m.transitionToOperationalMode(m.previousMode)
+
+	case allocateManyView:
+		if m.formAlloc.confirmed {
+			return m.allocateMany(m.allocateRequest())
+		}
+		return m.transitionToOperationalMode(listView)
+
+	case bulkView:
+		if m.formBulk.confirmed {
+			// Progress shows over the list while the change is applied
+			apply := m.applyBulk(m.bulkTargets(), m.bulkChange())
+			return tea.Batch(apply, m.transitionToOperationalMode(listView))
 		}
+		return m.transitionToOperationalMode(listView)
 
 	case deleteConfirmView:
 		if m.formConfirmed {
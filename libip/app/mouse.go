@@ -0,0 +1,175 @@
+package app
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doubleClickTime is the longest gap between the clicks of a double click
+const doubleClickTime = 400 * time.Millisecond
+
+// wheelStep is the number of list rows one notch of the wheel scrolls
+const wheelStep = 3
+
+// mouseClick is a left click, remembered to recognise a double click
+type mouseClick struct {
+	at     time.Time
+	target string
+}
+
+// mapTop is the line of the map view on which the grid starts, below the
+// scale line and a blank line
+const mapTop = 2
+
+// dashboardTop is the line of the dashboard on which the first utilisation
+// row is drawn, below the thresholds, a blank line and the table's top border,
+// header and rule
+const dashboardTop = 5
+
+// handleMouse selects with a click, opens with a double click and scrolls
+// with the wheel. Clicking a dashboard row drills into it. The right and back
+// buttons go up a level.
+func (m *mainModel) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.palette != nil || m.search.Focused() || m.editing() || msg.Action != tea.MouseActionPress {
+		return nil
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.scroll(-1)
+	case tea.MouseButtonWheelDown:
+		m.scroll(1)
+	case tea.MouseButtonRight, tea.MouseButtonBackward:
+		if m.operationalMode != listView {
+			return m.runAction("back")
+		}
+	case tea.MouseButtonLeft:
+		top := lipgloss.Height(m.breadcrumbBar())
+		if msg.Y < top {
+			return m.clickCrumb(msg.X)
+		}
+		switch m.operationalMode {
+		case listView:
+			return m.clickList(msg.X, msg.Y-top)
+		case mapView:
+			return m.clickMap(msg.X, msg.Y-top)
+		case dashboardView:
+			return m.clickDashboard(msg.Y - top)
+		}
+	}
+	return nil
+}
+
+// doubleClick records a click on target and reports whether it completes a
+// double click
+func (m *mainModel) doubleClick(target string) bool {
+	now := time.Now()
+	last := m.lastClick
+	if last.target == target && now.Sub(last.at) < doubleClickTime {
+		m.lastClick = mouseClick{}
+		return true
+	}
+	m.lastClick = mouseClick{at: now, target: target}
+	return false
+}
+
+// scroll moves the list cursor, or the map cursor by a row, a notch of the
+// wheel at a time
+func (m *mainModel) scroll(notches int) {
+	switch m.operationalMode {
+	case listView:
+		m.cursor += notches * wheelStep
+		m.clampCursor()
+	case mapView:
+		m.moveMapCursor(0, notches)
+	}
+}
+
+// listTop is the line of the list view on which the first record is drawn,
+// below the filter bar, the search bar, a blank line and the table header
+func (m *mainModel) listTop(visible int) int {
+	top := 4
+	if bar := m.searchBar(visible); bar != "" {
+		top += lipgloss.Height(bar)
+	}
+	return top
+}
+
+// clickList selects the record on line y of the list, opening it on a double
+// click
+func (m *mainModel) clickList(x, y int) tea.Cmd {
+	if left, _ := m.paneWidths(); m.splitPane() && x >= left {
+		return nil
+	}
+	visible := m.visibleRecords()
+	start, end := m.listWindow(len(visible))
+	i := start + y - m.listTop(len(visible))
+	if i < start || i >= end {
+		return nil
+	}
+	m.cursor = i
+	if id := visible[i].ID(); m.doubleClick(id) {
+		return func() tea.Msg { return enterDetailViewMsg{recordID: id} }
+	}
+	return nil
+}
+
+// clickMap drills into the block under the pointer, or selects the address
+// there and opens its record on a double click
+func (m *mainModel) clickMap(x, y int) tea.Cmd {
+	g := m.mapLayout()
+	cells := m.mapCells(g)
+	x -= mapLabelWidth(cells) + 1
+	row, col := y-mapTop, x/2
+	if x < 0 || col >= g.cols || row < 0 || row >= g.rows {
+		return nil
+	}
+	m.mapCursor = row*g.cols + col
+	if g.cellBits > 0 || m.doubleClick(cells[m.mapCursor].prefix.String()) {
+		return m.runMapAction("select")
+	}
+	return nil
+}
+
+// clickDashboard drills into the utilisation row on line y: a network opens
+// its records, a range its records of the range's role
+func (m *mainModel) clickDashboard(y int) tea.Cmd {
+	i := y - dashboardTop
+	if i < 0 || i >= len(m.usages) {
+		return nil
+	}
+	u := m.usages[i]
+	if u.Network != m.network.Name {
+		m.resetNetwork(u.Network)
+	}
+	search := ""
+	if u.Kind == "range" && u.Role != "" {
+		search = "role:" + u.Role
+	}
+	m.search.SetValue(search)
+	m.cursor = 0
+	return m.transitionToOperationalMode(listView)
+}
+
+// clickCrumb returns the address map to the level of the breadcrumb at
+// column x
+func (m *mainModel) clickCrumb(x int) tea.Cmd {
+	if m.operationalMode != mapView {
+		return nil
+	}
+	pos := 1
+	for i, crumb := range m.breadcrumbs() {
+		w := lipgloss.Width(crumb)
+		if x >= pos && x < pos+w {
+			// The first crumb is the network, the second its whole prefix
+			if level := i - 1; level >= 0 && level < len(m.mapStack) {
+				m.mapStack = m.mapStack[:level]
+				m.mapCursor = 0
+			}
+			return nil
+		}
+		pos += w + lipgloss.Width(" › ")
+	}
+	return nil
+}
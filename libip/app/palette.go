@@ -0,0 +1,203 @@
+package app
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// palette is the command palette while it is open
+type palette struct {
+	input    textinput.Model
+	cursor   int
+	networks []record.Network
+	jumps    bool // Offer only bookmarks and recent records
+}
+
+// paletteEntry is a command offered by the palette
+type paletteEntry struct {
+	title string
+	key   string  // Key bound to the command, if any
+	msg   tea.Msg // Dispatched when the entry is chosen
+}
+
+// paletteHidden are actions left out of the palette: moving around is only
+// useful from the keyboard
+var paletteHidden = map[string]bool{
+	"up": true, "down": true, "left": true, "right": true,
+	"page_up": true, "page_down": true, "top": true, "bottom": true,
+	"apply": true, "cancel": true, "palette": true,
+	"mark": true, "mark_up": true, "mark_down": true,
+}
+
+// paletteRows is the number of entries shown at once
+const paletteRows = 10
+
+// openPalette shows the command palette and loads the networks it offers
+// to switch to
+func (m *mainModel) openPalette() tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = ": "
+	ti.Placeholder = "command, network or address"
+	m.palette = &palette{input: ti}
+	return tea.Batch(m.palette.input.Focus(), m.loadNetworks())
+}
+
+// paletteEntries lists every command available in the current mode: its
+// actions, then switching network, going to an address, exporting and
+// changing theme
+func (m *mainModel) paletteEntries() []paletteEntry {
+	if m.palette.jumps {
+		return m.jumpEntries()
+	}
+	var entries []paletteEntry
+	actions := m.keys.actions()
+	for _, group := range modeActions[m.operationalMode] {
+		for _, name := range group {
+			if paletteHidden[name] {
+				continue
+			}
+			b := actions[name]
+			hint := ""
+			if b.Enabled() {
+				hint = b.Help().Key
+			}
+			entries = append(entries, paletteEntry{title: b.Help().Desc, key: hint, msg: actionMsg{action: name}})
+		}
+	}
+
+	for _, n := range m.palette.networks {
+		if n.Name != m.network.Name {
+			entries = append(entries, paletteEntry{
+				title: fmt.Sprintf("switch network %s (%s)", n.Name, n.Prefix),
+				msg:   switchNetworkMsg{network: n.Name},
+			})
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(m.palette.input.Value())); err == nil {
+		for _, r := range m.records {
+			if r.Addr == addr {
+				entries = append(entries, paletteEntry{title: "go to " + r.ID(), msg: enterDetailViewMsg{recordID: r.ID()}})
+			}
+		}
+	}
+	if m.operationalMode == listView {
+		for _, format := range []string{"csv", "json"} {
+			entries = append(entries, paletteEntry{title: "export listed records as " + format, msg: exportMsg{format: format}})
+		}
+	}
+	for _, name := range styles.ThemeNames() {
+		entries = append(entries, paletteEntry{title: "theme " + name, msg: themeMsg{theme: name}})
+	}
+	return entries
+}
+
+// paletteMatch is an entry matching the palette's query
+type paletteMatch struct {
+	paletteEntry
+	pos []int
+}
+
+// paletteMatches filters the entries by the query, closest matches first
+func (m *mainModel) paletteMatches() []paletteMatch {
+	pattern := foldCase(strings.TrimSpace(m.palette.input.Value()))
+	var matches []paletteMatch
+	for _, e := range m.paletteEntries() {
+		if pattern == "" {
+			matches = append(matches, paletteMatch{paletteEntry: e})
+			continue
+		}
+		if pos, ok := fuzzyMatch(pattern, e.title); ok {
+			matches = append(matches, paletteMatch{e, pos})
+		}
+	}
+	// Tighter matches first, so typing a word brings it to the top
+	sort.SliceStable(matches, func(i, j int) bool {
+		return spread(matches[i].pos) < spread(matches[j].pos)
+	})
+	return matches
+}
+
+// spread is the distance between the first and last matched characters
+func spread(pos []int) int {
+	if len(pos) == 0 {
+		return 0
+	}
+	return pos[len(pos)-1] - pos[0]
+}
+
+// handlePaletteKey edits the palette query and moves between its entries.
+// Enter dispatches the selected entry's message; esc closes the palette.
+func (m *mainModel) handlePaletteKey(msg tea.KeyMsg) tea.Cmd {
+	p := m.palette
+	switch {
+	case key.Matches(msg, m.keys.Apply):
+		matches := m.paletteMatches()
+		m.palette = nil
+		if p.cursor >= len(matches) {
+			return nil
+		}
+		chosen := matches[p.cursor].msg
+		return func() tea.Msg { return chosen }
+	case key.Matches(msg, m.keys.Cancel):
+		m.palette = nil
+		return nil
+	}
+	switch msg.Type {
+	case tea.KeyUp, tea.KeyShiftTab:
+		p.cursor = max(p.cursor-1, 0)
+		return nil
+	case tea.KeyDown, tea.KeyTab:
+		p.cursor = min(p.cursor+1, max(len(m.paletteMatches())-1, 0))
+		return nil
+	}
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	p.cursor = 0
+	return cmd
+}
+
+// paletteView renders the query and the matching entries around the cursor
+func (m *mainModel) paletteView() string {
+	p := m.palette
+	matches := m.paletteMatches()
+	start := max(0, p.cursor-paletteRows+1)
+	end := min(start+paletteRows, len(matches))
+
+	// Keys line up in a column after the longest title shown
+	titleWidth := 0
+	for _, e := range matches[start:end] {
+		titleWidth = max(titleWidth, lipgloss.Width(e.title))
+	}
+
+	lines := []string{p.input.View(), ""}
+	for i, e := range matches[start:end] {
+		base, marker := lipgloss.NewStyle(), "  "
+		if start+i == p.cursor {
+			base, marker = styles.SelectedStyle, "▸ "
+		}
+		line := base.Render(marker) + highlight(e.title, e.pos, base)
+		if e.key != "" {
+			line += strings.Repeat(" ", titleWidth-lipgloss.Width(e.title)+2) + styles.InfoStyle.Render(e.key)
+		}
+		lines = append(lines, line)
+	}
+	if len(matches) == 0 {
+		lines = append(lines, styles.InfoStyle.Render("No matching commands"))
+	}
+	lines = append(lines, "", styles.InfoStyle.Render(fmt.Sprintf("%d of %d commands", len(matches), len(m.paletteEntries()))))
+
+	width := 60
+	if m.width > 0 {
+		width = min(width, m.width-2)
+	}
+	return styles.BoxStyle.Width(width).Render(strings.Join(lines, "\n"))
+}
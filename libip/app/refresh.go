@@ -0,0 +1,116 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// refreshInterval is how often the database is checked for changes made by
+// other processes
+const refreshInterval = 2 * time.Second
+
+// highlightTime is how long rows changed elsewhere stay highlighted
+const highlightTime = 3 * time.Second
+
+// pollRevision schedules the next check of the database revision
+func pollRevision() tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg { return revisionTickMsg{} })
+}
+
+// checkRevision reads the database revision
+func (m *mainModel) checkRevision() tea.Cmd {
+	return func() tea.Msg {
+		rev, err := m.db.Revision()
+		return revisionMsg{revision: rev, err: err}
+	}
+}
+
+// refresh reloads what the current view shows, or returns nil if the view
+// cannot be refreshed now
+func (m *mainModel) refresh() tea.Cmd {
+	if m.at != nil || m.bulk != nil {
+		return nil
+	}
+	switch m.operationalMode {
+	case listView, mapView:
+		load := m.loadRecordList()
+		return func() tea.Msg {
+			msg := load()
+			if loaded, ok := msg.(recordsLoadedMsg); ok {
+				loaded.refresh = true
+				return loaded
+			}
+			return msg
+		}
+	case detailView:
+		id, network := m.currentRecordID, m.network.Name
+		load := m.loadRecordDetail(id)
+		return func() tea.Msg {
+			if _, err := m.db.GetRecord(network, id); err != nil {
+				return recordRemovedMsg{recordID: id}
+			}
+			return load()
+		}
+	case dashboardView:
+		return m.loadUtilisation()
+	}
+	return nil
+}
+
+// applyRefresh takes in reloaded records, keeping the cursor on the selected
+// record and highlighting the rows that changed
+func (m *mainModel) applyRefresh(msg recordsLoadedMsg) tea.Cmd {
+	selected, ok := m.selectedRecord()
+	changed, removed := changedRecords(m.records, msg.records)
+	m.network, m.records = msg.network, msg.records
+	m.clampCursor()
+	if ok {
+		for i, r := range m.visibleRecords() {
+			if r.ID() == selected.ID() {
+				m.cursor = i
+			}
+		}
+	}
+	for id := range m.marked {
+		if !slices.ContainsFunc(m.records, func(r record.Record) bool { return r.ID() == id }) {
+			delete(m.marked, id)
+		}
+	}
+
+	n := len(changed) + removed
+	if n == 0 {
+		return nil
+	}
+	m.changed = changed
+	m.changedSeq++
+	seq := m.changedSeq
+	text := fmt.Sprintf("%d records changed elsewhere", n)
+	if n == 1 {
+		text = "1 record changed elsewhere"
+	}
+	return tea.Batch(
+		m.setStatus(severityInfo, text),
+		tea.Tick(highlightTime, func(time.Time) tea.Msg { return changedExpiredMsg{seq: seq} }),
+	)
+}
+
+// changedRecords returns the IDs of the records that are new or different in
+// the reloaded list, and how many records were removed
+func changedRecords(old, reloaded []record.Record) (map[string]bool, int) {
+	before := make(map[string]record.Record, len(old))
+	for _, r := range old {
+		before[r.ID()] = r
+	}
+	changed := make(map[string]bool)
+	for _, r := range reloaded {
+		if prev, ok := before[r.ID()]; !ok || !prev.Equal(r) {
+			changed[r.ID()] = true
+		}
+		delete(before, r.ID())
+	}
+	return changed, len(before)
+}
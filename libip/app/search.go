@@ -0,0 +1,325 @@
+package app
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"unicode"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Searchable record fields, in the order free terms are tried against them
+const (
+	fieldAddr        = "addr"
+	fieldHostname    = "hostname"
+	fieldMAC         = "mac"
+	fieldOwner       = "owner"
+	fieldDescription = "description"
+	fieldTags        = "tags"
+)
+
+var searchFields = []string{fieldAddr, fieldHostname, fieldMAC, fieldOwner, fieldDescription, fieldTags}
+
+// customPrefix qualifies a search term with a custom field, e.g. field.rack:a1
+const customPrefix = "field."
+
+// fieldAliases maps query qualifiers to the field they search
+var fieldAliases = map[string]string{
+	"addr":        fieldAddr,
+	"address":     fieldAddr,
+	"host":        fieldHostname,
+	"hostname":    fieldHostname,
+	"mac":         fieldMAC,
+	"owner":       fieldOwner,
+	"desc":        fieldDescription,
+	"description": fieldDescription,
+	"tag":         fieldTags,
+	"tags":        fieldTags,
+}
+
+// fieldTerm is a qualified term that must appear within one field
+type fieldTerm struct {
+	field string
+	value string
+}
+
+// searchQuery is a parsed search bar query. Free terms fuzzy-match any field;
+// qualified terms narrow to one field, a state, a role or a containing prefix.
+// Repeated state, role or in: qualifiers match any of their values.
+type searchQuery struct {
+	terms    []string
+	fields   []fieldTerm
+	states   []record.State
+	roles    []record.Role
+	prefixes []netip.Prefix
+}
+
+// parseQuery splits the search bar text into free and qualified terms
+func parseQuery(s string) (searchQuery, error) {
+	var q searchQuery
+	for _, word := range strings.Fields(s) {
+		name, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			q.terms = append(q.terms, foldCase(word))
+			continue
+		}
+		switch name = strings.ToLower(name); name {
+		case "state":
+			st, err := record.ParseState(value)
+			if err != nil {
+				return q, err
+			}
+			q.states = append(q.states, st)
+		case "role":
+			ro, err := record.ParseRole(value)
+			if err != nil {
+				return q, err
+			}
+			q.roles = append(q.roles, ro)
+		case "in":
+			p, err := netip.ParsePrefix(value)
+			if err != nil {
+				return q, fmt.Errorf("in: %s", err)
+			}
+			q.prefixes = append(q.prefixes, p.Masked())
+		default:
+			field, ok := fieldAliases[name]
+			if strings.HasPrefix(name, customPrefix) && len(name) > len(customPrefix) {
+				field, ok = name, true
+			}
+			if !ok {
+				return q, fmt.Errorf("unknown field %q", name)
+			}
+			q.fields = append(q.fields, fieldTerm{field: field, value: foldCase(value)})
+		}
+	}
+	return q, nil
+}
+
+// empty reports whether the query matches everything
+func (q searchQuery) empty() bool {
+	return len(q.terms) == 0 && len(q.fields) == 0 && len(q.states) == 0 &&
+		len(q.roles) == 0 && len(q.prefixes) == 0
+}
+
+// highlights maps a field to the rune positions matched within it
+type highlights map[string][]int
+
+// match reports whether a record satisfies every term of the query, and
+// which characters of each field matched
+func (q searchQuery) match(n record.Network, r record.Record) (highlights, bool) {
+	if len(q.states) > 0 && !containsState(q.states, r.State) {
+		return nil, false
+	}
+	if len(q.roles) > 0 && !containsRole(q.roles, n.RoleOf(r.Addr)) {
+		return nil, false
+	}
+	if len(q.prefixes) > 0 && !containsAddr(q.prefixes, r.Addr) {
+		return nil, false
+	}
+
+	values := recordFields(r)
+	hl := make(highlights)
+	for _, ft := range q.fields {
+		pos, ok := indexFold(values[ft.field], ft.value)
+		if !ok {
+			return nil, false
+		}
+		hl[ft.field] = append(hl[ft.field], pos...)
+	}
+	for _, term := range q.terms {
+		matched := false
+		for _, f := range searchFields {
+			if pos, ok := fuzzyMatch(term, values[f]); ok {
+				hl[f] = append(hl[f], pos...)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, false
+		}
+	}
+	return hl, true
+}
+
+// recordFields returns the searchable text of a record by field, custom
+// fields keyed as field.<name>
+func recordFields(r record.Record) map[string]string {
+	values := map[string]string{
+		fieldAddr:        r.ID(),
+		fieldHostname:    r.Hostname,
+		fieldMAC:         r.MAC,
+		fieldOwner:       r.Owner,
+		fieldDescription: r.Description,
+		fieldTags:        strings.Join(r.Tags, ","),
+	}
+	for name, v := range r.Fields {
+		values[customPrefix+name] = v
+	}
+	return values
+}
+
+// fuzzyMatch matches pattern against text ignoring case, preferring a
+// contiguous substring and otherwise accepting the pattern's characters in
+// order. It returns the rune positions matched.
+func fuzzyMatch(pattern, text string) ([]int, bool) {
+	if pos, ok := indexFold(text, pattern); ok {
+		return pos, true
+	}
+
+	want := []rune(pattern)
+	var pos []int
+	for i, c := range []rune(text) {
+		if len(pos) < len(want) && unicode.ToLower(c) == want[len(pos)] {
+			pos = append(pos, i)
+		}
+	}
+	return pos, len(pos) == len(want) && len(want) > 0
+}
+
+// foldCase lower-cases s a rune at a time, so that it keeps one rune for
+// each rune of s
+func foldCase(s string) string {
+	return strings.Map(unicode.ToLower, s)
+}
+
+// indexFold finds the first occurrence of the case-folded pattern in text,
+// comparing rune by rune, and returns the rune positions it covers
+func indexFold(text, pattern string) ([]int, bool) {
+	runes, want := []rune(text), []rune(pattern)
+	if len(want) == 0 {
+		return nil, false
+	}
+next:
+	for start := 0; start+len(want) <= len(runes); start++ {
+		for j, c := range want {
+			if unicode.ToLower(runes[start+j]) != c {
+				continue next
+			}
+		}
+		pos := make([]int, len(want))
+		for j := range pos {
+			pos[j] = start + j
+		}
+		return pos, true
+	}
+	return nil, false
+}
+
+func containsState(states []record.State, st record.State) bool {
+	for _, s := range states {
+		if s == st {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRole(roles []record.Role, ro record.Role) bool {
+	for _, r := range roles {
+		if r == ro {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlight renders text with the matched rune positions emphasised. The
+// rest is rendered in the row's style, which the match styling would
+// otherwise reset.
+func highlight(text string, pos []int, base lipgloss.Style) string {
+	if len(pos) == 0 {
+		return text
+	}
+	marked := make(map[int]bool, len(pos))
+	for _, p := range pos {
+		marked[p] = true
+	}
+	var b, run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			b.WriteString(base.Render(run.String()))
+			run.Reset()
+		}
+	}
+	for i, c := range []rune(text) {
+		if marked[i] {
+			flush()
+			b.WriteString(styles.MatchStyle.Inherit(base).Render(string(c)))
+		} else {
+			run.WriteRune(c)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// newSearchInput creates the list view's search bar
+func newSearchInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "/ "
+	ti.Placeholder = "search, or in:10.0.4.0/24 state:reserved role:static host:web"
+	return ti
+}
+
+// searchQuery parses the search bar text
+func (m *mainModel) searchQuery() (searchQuery, error) {
+	return parseQuery(m.search.Value())
+}
+
+// handleSearchKey edits the search bar while it has focus. Enter keeps the
+// query and returns to the list; esc clears it.
+func (m *mainModel) handleSearchKey(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keys.Apply):
+		m.search.Blur()
+		return nil
+	case key.Matches(msg, m.keys.Cancel):
+		m.search.Reset()
+		m.search.Blur()
+		m.clampCursor()
+		return nil
+	}
+	switch msg.Type {
+	case tea.KeyUp:
+		m.cursor--
+		m.clampCursor()
+		return nil
+	case tea.KeyDown:
+		m.cursor++
+		m.clampCursor()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.cursor = 0
+	return cmd
+}
+
+// searchBar renders the search input with the match count or query error
+func (m *mainModel) searchBar(matches int) string {
+	if !m.search.Focused() && m.search.Value() == "" {
+		return ""
+	}
+	status := styles.InfoStyle.Render(fmt.Sprintf("%d of %d", matches, len(m.records)))
+	if _, err := m.searchQuery(); err != nil {
+		status = styles.ErrorStyle.Render(err.Error())
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, m.search.View(), "  ", status)
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		want          []int
+		ok            bool
+	}{
+		{"web", "Web01", []int{0, 1, 2}, true},
+		{"w1", "web01", []int{0, 4}, true},
+		{"x", "ȺȺȺx", []int{3}, true},
+		{"ⱥx", "ȺȺȺx", []int{2, 3}, true},
+		{foldCase("İ"), "aİb", []int{1}, true},
+		{"b", "İİb", []int{2}, true},
+		{"zz", "ȺȺȺx", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := fuzzyMatch(tt.pattern, tt.text)
+		if ok != tt.ok || ok && !slices.Equal(got, tt.want) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %t; want %v, %t", tt.pattern, tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSearchQueryMatch(t *testing.T) {
+	n := record.Network{Name: "lab", Prefix: netip.MustParsePrefix("10.0.0.0/24")}
+	r := record.Record{
+		Network:     "lab",
+		Addr:        netip.MustParseAddr("10.0.0.5"),
+		Hostname:    "ȺȺȺx",
+		Description: "İstanbul rack",
+	}
+	tests := []struct {
+		query string
+		field string
+		want  []int
+		ok    bool
+	}{
+		{"host:x", fieldHostname, []int{3}, true},
+		{"desc:İST", fieldDescription, []int{0, 1, 2}, true},
+		{"desc:rack", fieldDescription, []int{9, 10, 11, 12}, true},
+		{"x", fieldHostname, []int{3}, true},
+		{"host:y", "", nil, false},
+	}
+	for _, tt := range tests {
+		q, err := parseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("parseQuery(%q): %s", tt.query, err)
+		}
+		hl, ok := q.match(n, r)
+		if ok != tt.ok || ok && !slices.Equal(hl[tt.field], tt.want) {
+			t.Errorf("%q matched %v, %t; want %s %v, %t", tt.query, hl, ok, tt.field, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// recentLimit is the number of recently opened records remembered
+const recentLimit = 10
+
+// session captures where the user is so the next launch can pick up there
+func (m *mainModel) session() record.Session {
+	s := record.Session{
+		Network:   m.network.Name,
+		Mode:      "list",
+		Search:    m.search.Value(),
+		Columns:   m.showColumns,
+		MapColour: m.mapColour.String(),
+		MapStack:  m.mapStack,
+		Bookmarks: m.bookmarks,
+		Recent:    m.recent,
+	}
+	if m.stateFilter != nil {
+		s.StateFilter = m.stateFilter.String()
+	}
+	if saved := m.activeSearch(); saved != nil {
+		s.SavedSearch = saved.name
+	}
+
+	switch m.operationalMode {
+	case mapView:
+		s.Mode = "map"
+	case dashboardView:
+		s.Mode = "dashboard"
+	case detailView, editView, deleteConfirmView:
+		s.Mode, s.Record = "detail", m.currentRecordID
+	default:
+		if r, ok := m.selectedRecord(); ok {
+			s.Record = r.ID()
+		}
+	}
+	return s
+}
+
+// saveSession stores the session in the database
+func (m *mainModel) saveSession() tea.Cmd {
+	s := m.session()
+	return func() tea.Msg {
+		if err := m.db.SaveSession(s); err != nil {
+			return errorMsg{context: "saving session", err: err}
+		}
+		return nil
+	}
+}
+
+// restoreSession returns to the view of a saved session. The network has
+// already been loaded; settings naming things that no longer exist are
+// dropped.
+func (m *mainModel) restoreSession(s record.Session) tea.Cmd {
+	m.bookmarks, m.recent = s.Bookmarks, s.Recent
+	if s.Network != m.network.Name {
+		// The session's network is gone and the default was loaded instead
+		return m.transitionToOperationalMode(listView)
+	}
+
+	m.search.SetValue(s.Search)
+	if st, err := record.ParseState(s.StateFilter); err == nil {
+		m.stateFilter = &st
+	}
+	for i, saved := range m.searches {
+		if saved.name == s.SavedSearch {
+			m.savedSearch = i
+		}
+	}
+	m.showColumns = s.Columns
+	if s.MapColour == colourByRole.String() {
+		m.mapColour = colourByRole
+	}
+	outer := m.network.Prefix
+	for _, p := range s.MapStack {
+		if p.Bits() <= outer.Bits() || !outer.Contains(p.Addr()) {
+			break
+		}
+		m.mapStack = append(m.mapStack, p)
+		outer = p
+	}
+
+	switch s.Mode {
+	case "map":
+		return m.transitionToOperationalMode(mapView)
+	case "dashboard":
+		return m.transitionToOperationalMode(dashboardView)
+	}
+	m.pendingRecord, m.pendingOpen = s.Record, s.Mode == "detail"
+	return m.transitionToOperationalMode(listView)
+}
+
+// selectPending moves the list cursor to the record waiting to be selected
+// once the records have loaded, opening it if asked to
+func (m *mainModel) selectPending() tea.Cmd {
+	id, open := m.pendingRecord, m.pendingOpen
+	if id == "" {
+		return nil
+	}
+	m.pendingRecord, m.pendingOpen = "", false
+
+	if !slices.ContainsFunc(m.records, func(r record.Record) bool { return r.ID() == id }) {
+		return notify(severityWarn, fmt.Sprintf("%s no longer has a record for %s", m.network.Name, id))
+	}
+	for i, r := range m.visibleRecords() {
+		if r.ID() == id {
+			m.cursor = i
+		}
+	}
+	if open {
+		return func() tea.Msg { return enterDetailViewMsg{recordID: id} }
+	}
+	return nil
+}
+
+// visit puts a record at the front of the recently opened records
+func (m *mainModel) visit(id string) {
+	b := record.Bookmark{Network: m.network.Name, Record: id}
+	recent := []record.Bookmark{b}
+	for _, r := range m.recent {
+		if r != b && len(recent) < recentLimit {
+			recent = append(recent, r)
+		}
+	}
+	m.recent = recent
+}
+
+// toggleBookmark bookmarks a record of the current network, or removes its
+// bookmark, and saves the session so the change is kept
+func (m *mainModel) toggleBookmark(id string) tea.Cmd {
+	b := record.Bookmark{Network: m.network.Name, Record: id}
+	text := "Bookmarked " + id
+	if i := slices.Index(m.bookmarks, b); i >= 0 {
+		m.bookmarks = slices.Delete(slices.Clone(m.bookmarks), i, i+1)
+		text = "Removed the bookmark of " + id
+	} else {
+		m.bookmarks = append(slices.Clone(m.bookmarks), b)
+	}
+	return tea.Batch(m.saveSession(), m.setStatus(severitySuccess, text))
+}
+
+// openJumps shows the palette listing only bookmarks and recent records
+func (m *mainModel) openJumps() tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = "' "
+	ti.Placeholder = "bookmark or recent record"
+	m.palette = &palette{input: ti, jumps: true}
+	return m.palette.input.Focus()
+}
+
+// jumpEntries lists the bookmarks, then the recent records not bookmarked
+func (m *mainModel) jumpEntries() []paletteEntry {
+	var entries []paletteEntry
+	for _, b := range m.bookmarks {
+		entries = append(entries, paletteEntry{title: "bookmark " + b.String(), msg: jumpMsg{to: b}})
+	}
+	for _, b := range m.recent {
+		if !slices.Contains(m.bookmarks, b) {
+			entries = append(entries, paletteEntry{title: "recent " + b.String(), msg: jumpMsg{to: b}})
+		}
+	}
+	return entries
+}
+
+// jumpTo opens a bookmarked or recent record, switching network if need be
+func (m *mainModel) jumpTo(b record.Bookmark) tea.Cmd {
+	if b.Network != m.network.Name {
+		m.resetNetwork(b.Network)
+	}
+	m.pendingRecord, m.pendingOpen = b.Record, true
+	return m.transitionToOperationalMode(listView)
+}
@@ -57,6 +57,11 @@ const (
 	createView
 	editView
 	deleteConfirmView
+	mapView
+	dashboardView
+	timeTravelView
+	bulkView
+	allocateManyView
 	// Easy to add more modes as UI design evolves
 )
 
@@ -72,6 +77,16 @@ func (om operationalMode) String() string {
 		return "edit view"
 	case deleteConfirmView:
 		return "delete confirm view"
+	case mapView:
+		return "map view"
+	case dashboardView:
+		return "dashboard view"
+	case timeTravelView:
+		return "time travel view"
+	case bulkView:
+		return "bulk view"
+	case allocateManyView:
+		return "allocate many view"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// severity ranks status messages, deciding their style and how long they
+// stay on screen
+type severity uint8
+
+const (
+	severityInfo severity = iota
+	severitySuccess
+	severityWarn
+	severityError
+)
+
+// statusTimeouts is how long a message of each severity is shown
+var statusTimeouts = map[severity]time.Duration{
+	severityInfo:    4 * time.Second,
+	severitySuccess: 4 * time.Second,
+	severityWarn:    8 * time.Second,
+	severityError:   15 * time.Second,
+}
+
+// statusLine is the message shown in the status bar
+type statusLine struct {
+	text     string
+	severity severity
+	seq      int // Distinguishes messages so a timer only dismisses its own
+}
+
+// statusExpiredMsg dismisses the status message with the given sequence
+// number, unless it has been replaced since
+type statusExpiredMsg struct {
+	seq int
+}
+
+// setStatus shows a message in the status bar and schedules its dismissal
+func (m *mainModel) setStatus(sev severity, text string) tea.Cmd {
+	seq := m.status.seq + 1
+	m.status = statusLine{text: text, severity: sev, seq: seq}
+	return tea.Tick(statusTimeouts[sev], func(time.Time) tea.Msg {
+		return statusExpiredMsg{seq: seq}
+	})
+}
+
+// clearStatus dismisses the status message
+func (m *mainModel) clearStatus() {
+	m.status = statusLine{seq: m.status.seq}
+}
+
+// notify returns a command that shows a status message
+func notify(sev severity, text string) tea.Cmd {
+	return func() tea.Msg { return statusMsg{severity: sev, text: text} }
+}
+
+// statusBar renders the status message on a single line, marked as well as
+// coloured by severity so it reads without colour
+func (m *mainModel) statusBar() string {
+	if m.bulk != nil {
+		return m.bulkStatus()
+	}
+	if m.status.text == "" {
+		return ""
+	}
+	var style lipgloss.Style
+	var mark string
+	switch m.status.severity {
+	case severitySuccess:
+		style, mark = styles.StatusStyle, "✓ "
+	case severityWarn:
+		style, mark = styles.WarnStyle, "! "
+	case severityError:
+		style, mark = styles.ErrorStyle, "✗ "
+	default:
+		style = styles.InfoStyle
+	}
+	if m.width > 0 {
+		style = style.MaxWidth(m.width)
+	}
+	return style.Render(mark + m.status.text)
+}
@@ -99,9 +99,12 @@ func (m *mainModel) transitionToOperationalMode(newMode operationalMode) tea.Cmd
 
 	// Mode entry actions
 	switch newMode {
-	case listView:
+	case listView, mapView:
 		return m.loadRecordList()
 
+	case dashboardView:
+		return m.loadUtilisation()
+
 	case detailView:
 		// Requires a record ID to be set before calling this
 		if m.currentRecordID != "" {
@@ -119,6 +122,18 @@ func (m *mainModel) transitionToOperationalMode(newMode operationalMode) tea.Cmd
 			return m.form.Init()
 		}
 
+	case bulkView:
+		m.form = m.bulkRecordForm()
+		return m.form.Init()
+
+	case allocateManyView:
+		m.form = m.allocateManyForm()
+		return m.form.Init()
+
+	case timeTravelView:
+		m.form = m.timeTravelForm()
+		return m.form.Init()
+
 	case deleteConfirmView:
 		// Requires a record ID to be set before calling this
 		if m.currentRecordID != "" {
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// changeSet is one undoable TUI operation: the audit entries it appended,
+// as returned by the database in the transaction that made the change
+type changeSet struct {
+	label   string
+	entries []record.AuditEntry
+}
+
+// recordChange pushes a completed operation onto the undo stack and returns
+// the alert check to run after it. A new change invalidates anything that was
+// undone before it.
+func (m *mainModel) recordChange(cs changeSet) tea.Cmd {
+	if len(cs.entries) == 0 {
+		return nil
+	}
+	m.undoStack = append(m.undoStack, cs)
+	m.redoStack = nil
+	return m.checkAlerts()
+}
+
+// undo reverts the most recent change of the session
+func (m *mainModel) undo() tea.Cmd {
+	if len(m.undoStack) == 0 {
+		return notify(severityInfo, "Nothing to undo")
+	}
+	cs := m.undoStack[len(m.undoStack)-1]
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	return func() tea.Msg {
+		return changeRevertedMsg{change: cs, undo: true, err: m.db.Undo(cs.entries)}
+	}
+}
+
+// redo reapplies the most recently undone change
+func (m *mainModel) redo() tea.Cmd {
+	if len(m.redoStack) == 0 {
+		return notify(severityInfo, "Nothing to redo")
+	}
+	cs := m.redoStack[len(m.redoStack)-1]
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	return func() tea.Msg {
+		return changeRevertedMsg{change: cs, undo: false, err: m.db.Redo(cs.entries)}
+	}
+}
+
+// handleChangeReverted moves a change between the stacks once it has been
+// undone or redone. A change that conflicts with later edits is dropped.
+func (m *mainModel) handleChangeReverted(msg changeRevertedMsg) tea.Cmd {
+	action, done := "redo", "Redid"
+	if msg.undo {
+		action, done = "undo", "Undid"
+	}
+	var status tea.Cmd
+	switch {
+	case msg.err != nil:
+		status = m.setStatus(severityError, fmt.Sprintf("Cannot %s %s: %v", action, msg.change.label, msg.err))
+	case msg.undo:
+		m.redoStack = append(m.redoStack, msg.change)
+		status = m.setStatus(severitySuccess, fmt.Sprintf("%s %s", done, msg.change.label))
+	default:
+		m.undoStack = append(m.undoStack, msg.change)
+		status = m.setStatus(severitySuccess, fmt.Sprintf("%s %s", done, msg.change.label))
+	}
+	var check tea.Cmd
+	if msg.err == nil {
+		check = m.checkAlerts()
+	}
+	return tea.Batch(status, check, m.transitionToOperationalMode(m.refreshMode()))
+}
+
+// refreshMode returns the mode to reload after the data changed underneath
+// the current view
+func (m *mainModel) refreshMode() operationalMode {
+	switch m.operationalMode {
+	case detailView:
+		if m.currentRecordID != "" {
+			return detailView
+		}
+	case mapView, dashboardView:
+		return m.operationalMode
+	}
+	return listView
+}
+
+// undoStatus describes the next undo and redo for the footer
+func (m *mainModel) undoStatus() string {
+	var s string
+	if len(m.undoStack) > 0 {
+		s = fmt.Sprintf("%s undo %s", m.keys.Undo.Help().Key, m.undoStack[len(m.undoStack)-1].label)
+	}
+	if len(m.redoStack) > 0 {
+		if s != "" {
+			s += " • "
+		}
+		s += fmt.Sprintf("%s redo %s", m.keys.Redo.Help().Key, m.redoStack[len(m.redoStack)-1].label)
+	}
+	return s
+}
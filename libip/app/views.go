@@ -1,11 +1,19 @@
 package app
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/report"
 	"github.com/bakedSpaceTime/binip/libip/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
 )
 
 func (m *mainModel) footerView() string {
-	helpView := m.help.View(m.keys)
+	helpView := m.help.View(m.contextHelp())
 	// Add debug status to expanded help view
 	if m.help.ShowAll {
 		debugStatus := "off"
@@ -14,61 +22,283 @@ func (m *mainModel) footerView() string {
 		}
 		helpView += "\n" + m.help.Styles.FullDesc.Render("Debug: ") + m.help.Styles.FullKey.Render(debugStatus)
 	}
+	if m.state == operational {
+		if s := m.undoStatus(); s != "" {
+			helpView = m.help.Styles.ShortDesc.Render(s) + "\n" + helpView
+		}
+	}
 	return styles.FooterStyle.
+		Width(m.width).
 		Render(helpView)
 }
 
 func (m *mainModel) onboardingView() string {
-	body := m.form.View()
+	return m.form.View()
+}
 
-	// Show error or status message if present
-	if m.msg != "" {
-		body = body + "\n\n" + styles.ErrorStyle.Render(m.msg)
+func (m *mainModel) operationalView() string {
+	if m.palette != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, m.paletteView(), m.modeView())
 	}
-
-	return body
+	return m.modeView()
 }
 
-func (m *mainModel) operationalView() string {
+// modeView renders the view of the current operational mode
+func (m *mainModel) modeView() string {
 	switch m.operationalMode {
 	case listView:
+		if m.splitPane() {
+			return m.splitListView()
+		}
 		return m.listView()
 	case detailView:
 		return m.detailView()
-	case createView, editView, deleteConfirmView:
+	case mapView:
+		return m.mapView()
+	case dashboardView:
+		return m.dashboardView()
+	case createView, editView, deleteConfirmView, timeTravelView, bulkView, allocateManyView:
 		// Form-based views
-		body := m.form.View()
-		if m.msg != "" {
-			body = body + "\n\n" + styles.StatusStyle.Render(m.msg)
-		}
-		return body
+		return m.form.View()
 	default:
 		// Fallback
 		return m.db.String()
 	}
 }
 
-// listView shows the list of records
+// listView shows the records of the current network
 func (m *mainModel) listView() string {
-	// TODO: Implement when CRUD UI is designed
-	// For now, show the database info as before
-	body := m.db.String()
+	// State filter bar with a badge and count per state
+	counts := m.stateCounts()
+	filters := []string{m.filterLabel("all", len(m.records), m.stateFilter == nil)}
+	for _, st := range record.States {
+		active := m.stateFilter != nil && *m.stateFilter == st
+		filters = append(filters, m.filterLabel(stateBadge(st), counts[st], active))
+	}
+
+	headers := []string{"address", "state", "role", "hostname", "owner", "description"}
+	if m.showColumns {
+		headers = append(headers, "tags")
+		for _, f := range m.network.Fields {
+			headers = append(headers, f.Name)
+		}
+	}
+	t := styles.BorderlessTable().Headers(headers...)
+	visible := m.visibleRecords()
+	q, _ := m.searchQuery()
+	start, end := m.listWindow(len(visible))
+	for i, r := range visible[start:end] {
+		hl, _ := q.match(m.network, r)
+		base := lipgloss.NewStyle()
+		if start+i == m.cursor {
+			base = styles.SelectedStyle
+		}
+		addr := highlight(r.ID(), hl[fieldAddr], base)
+		if len(m.marked) > 0 {
+			// Marks share the address column so the layout holds steady
+			mark := "  "
+			if m.marked[r.ID()] {
+				mark = "● "
+			}
+			addr = base.Render(mark) + addr
+		}
+		row := []string{
+			addr,
+			stateBadge(r.State),
+			roleLabel(m.network.RoleOf(r.Addr)),
+			highlight(r.Hostname, hl[fieldHostname], base),
+			highlight(r.Owner, hl[fieldOwner], base),
+			highlight(r.Description, hl[fieldDescription], base),
+		}
+		if m.showColumns {
+			row = append(row, highlight(strings.Join(r.Tags, ","), hl[fieldTags], base))
+			for _, f := range m.network.Fields {
+				row = append(row, highlight(r.Fields[f.Name], hl[customPrefix+f.Name], base))
+			}
+		}
+		t.Row(row...)
+	}
+	t.StyleFunc(func(row, _ int) lipgloss.Style {
+		switch {
+		case row == m.cursor-start:
+			return styles.SelectedStyle.Padding(0, 1)
+		case row >= 0 && m.changed[visible[start+row].ID()]:
+			return styles.ChangedStyle.Padding(0, 1)
+		}
+		return lipgloss.NewStyle().Padding(0, 1)
+	})
+	width := m.width
+	if m.splitPane() {
+		width, _ = m.paneWidths()
+	}
+	fitTable(t, width)
+
+	if s := m.activeSearch(); s != nil {
+		filters = append(filters, styles.AccentStyle.Render(fmt.Sprintf("search %s: %s", s.name, s.query)))
+	}
+	if len(m.marked) > 0 {
+		filters = append(filters, styles.AccentStyle.Render(fmt.Sprintf("%d marked", len(m.marked))))
+	}
+	lines := []string{strings.Join(filters, " ")}
+	if bar := m.searchBar(len(visible)); bar != "" {
+		lines = append(lines, bar)
+	}
+	body := lipgloss.JoinVertical(lipgloss.Left, append(lines, "", t.Render())...)
+	if len(visible) == 0 {
+		body += "\n" + styles.InfoStyle.Render("No records")
+	}
+	return body
+}
+
+// listWindow returns the slice bounds of the rows that fit on screen, keeping
+// the cursor visible
+func (m *mainModel) listWindow(n int) (start, end int) {
+	rows := m.pageSize()
+	if m.cursor >= rows {
+		start = m.cursor - rows + 1
+	}
+	end = min(start+rows, n)
+	return start, end
+}
+
+// pageSize is the number of list rows that fit on screen below the filter
+// bar, the search bar and the table header
+func (m *mainModel) pageSize() int {
+	return max(m.bodyHeight()-5, 3)
+}
+
+// filterLabel renders one entry of the state filter bar
+func (m *mainModel) filterLabel(label string, count int, active bool) string {
+	text := fmt.Sprintf("%s %d", label, count)
+	if active {
+		return styles.SelectedStyle.Render("▸ " + text)
+	}
+	return styles.InfoStyle.Render(text)
+}
 
-	if m.msg != "" {
-		body = body + "\n\n" + styles.StatusStyle.Render(m.msg)
+// stateBadge renders a lifecycle state as a coloured badge
+func stateBadge(s record.State) string {
+	switch s {
+	case record.Reserved:
+		return styles.ReservedBadge.Render(s.String())
+	case record.Allocated:
+		return styles.AllocatedBadge.Render(s.String())
+	case record.Deprecated:
+		return styles.DeprecatedBadge.Render(s.String())
+	case record.Quarantined:
+		return styles.QuarantinedBadge.Render(s.String())
+	default:
+		return styles.FreeBadge.Render(s.String())
 	}
+}
+
+// roleLabel renders a range role in its colour
+func roleLabel(role record.Role) string {
+	return styles.RoleStyle(role.String()).Render(role.String())
+}
 
+// dashboardView shows utilisation per network and range
+func (m *mainModel) dashboardView() string {
+	thresholds := styles.InfoStyle.Render(fmt.Sprintf("thresholds: %.0f%% utilisation, %.2f fragmentation",
+		m.config.Thresholds.Utilisation, m.config.Thresholds.Fragmentation))
+
+	body := lipgloss.JoinVertical(lipgloss.Left,
+		thresholds,
+		"",
+		report.Table(m.usages, m.config.Thresholds).Render(),
+	)
+	for _, u := range m.usages {
+		if u.Forecast == nil {
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", u.Network, u.Forecast)
+		if u.Forecast.DaysToFull <= m.config.Alerts.ForecastHorizon {
+			line = styles.ErrorStyle.Render(line)
+		}
+		body += "\n" + line
+	}
 	return body
 }
 
 // detailView shows details of a single record
 func (m *mainModel) detailView() string {
-	// TODO: Implement when CRUD UI is designed
-	body := "Detail view for record: " + m.currentRecordID
-
-	if m.msg != "" {
-		body = body + "\n\n" + styles.StatusStyle.Render(m.msg)
+	t := m.recordTable(m.currentRecord)
+	if m.forecast != nil {
+		t.Row("network forecast", m.forecast.String())
 	}
+	fitTable(t, m.width)
 
+	body := t.Render()
+	if len(m.history) > 0 {
+		body += "\n\n" + styles.HeaderStyle.Render("history") + "\n" + m.historyView()
+	}
 	return body
 }
+
+// recordTable lists the details of a record
+func (m *mainModel) recordTable(r record.Record) *table.Table {
+	t := styles.StyledTable()
+	t.Rows(
+		[]string{"address", r.ID()},
+		[]string{"network", r.Network},
+		[]string{"state", stateBadge(r.State)},
+		[]string{"range", rangeLabel(m.network, r)},
+		[]string{"hostname", r.Hostname},
+		[]string{"mac", r.MAC},
+		[]string{"owner", r.Owner},
+		[]string{"description", r.Description},
+		[]string{"created", formatTime(r.Created)},
+		[]string{"updated", formatTime(r.Updated)},
+	)
+	if len(r.Tags) > 0 {
+		t.Row("tags", strings.Join(r.Tags, ", "))
+	}
+	for _, f := range m.network.Fields {
+		t.Row(f.Name, r.Fields[f.Name])
+	}
+	if r.State == record.Quarantined {
+		t.Row("quarantine ends", formatTime(r.QuarantineEnds(m.network)))
+	}
+	return t
+}
+
+// historyLimit is the number of audit entries shown in the detail view
+const historyLimit = 8
+
+// historyView renders the most recent audit entries of the current record,
+// newest first
+func (m *mainModel) historyView() string {
+	t := styles.BorderlessTable().Headers("time", "user", "source", "action", "changes")
+	for i := len(m.history) - 1; i >= 0 && i >= len(m.history)-historyLimit; i-- {
+		e := m.history[i]
+		t.Row(formatTime(e.Time), e.User, e.Source, e.Action, e.Summary())
+	}
+	t.StyleFunc(func(row, _ int) lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1)
+	})
+	return t.Render()
+}
+
+// timeTravelLabel marks views showing the address plan as of a past moment
+func (m *mainModel) timeTravelLabel() string {
+	if m.at == nil {
+		return ""
+	}
+	return " " + styles.WarnStyle.Render(fmt.Sprintf("as of %s (read-only)", m.at.Format("2006-01-02 15:04")))
+}
+
+// rangeLabel describes the role range a record falls in
+func rangeLabel(n record.Network, r record.Record) string {
+	rg, ok := n.RangeOf(r.Addr)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", rg.Name, roleLabel(rg.Role))
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
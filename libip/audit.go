@@ -0,0 +1,51 @@
+package libip
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+)
+
+// Audit prints the audit log filtered by time, network, record and user
+func Audit(c *config.Config, since, network, rec, user, format string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	f := db.AuditFilter{Network: network, Record: rec, User: user}
+	if since != "" {
+		t, err := record.ParseTime(since, time.Now())
+		if err != nil {
+			return fmt.Errorf("--since: %s", err)
+		}
+		f.Since = t
+	}
+	entries, err := d.ListAudit(f)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		if entries == nil {
+			entries = []record.AuditEntry{}
+		}
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	t := styles.StyledTable().Headers("#", "time", "user", "source", "action", "network", "record", "changes")
+	for _, e := range entries {
+		t.Row(fmt.Sprint(e.Seq), e.Time.Local().Format(time.DateTime), e.User, e.Source,
+			e.Action, e.Network, e.Record, e.Summary())
+	}
+	fmt.Println(t.Render())
+	return nil
+}
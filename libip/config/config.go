@@ -1,23 +1,156 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
 )
 
 var defaultDb = "binip.db"
 var defaultDebugFile = "debug.log"
+var defaultQuarantine = 72 * time.Hour
 
 type Config struct {
 	DbFile      string
 	DebugFile   string
 	DebugWriter io.Writer
 	Debug       bool
+	// Quarantine is the hold-back period given to newly created networks
+	Quarantine time.Duration
+	Thresholds Thresholds
+	Alerts     Alerts
+	// Source names the interface making changes (cli or tui) in the audit log
+	Source string
+	// User overrides the OS user recorded in the audit log
+	User string
+	Keys Keys
+	// Theme names the colour theme: auto, dark, light, high-contrast,
+	// colour-blind or mono
+	Theme string
+	// Mouse enables clicking and wheel scrolling in the TUI
+	Mouse bool
+}
+
+// Thresholds are the utilisation levels above which reports flag a network
+type Thresholds struct {
+	// Utilisation is the percentage of usable addresses in use or reserved
+	Utilisation float64 `json:"utilisation"`
+	// Fragmentation is the fragmentation index, from 0 (all free space
+	// contiguous) to 1
+	Fragmentation float64 `json:"fragmentation"`
+}
+
+// Alerts configures where utilisation alerts are delivered and when growth
+// forecasts raise one
+type Alerts struct {
+	// Command is run through the shell with the alert as JSON on stdin
+	Command string `json:"command"`
+	// Webhook receives the alert as a JSON POST
+	Webhook string `json:"webhook"`
+	// Log writes a line per alert to stderr
+	Log bool `json:"log"`
+	// ForecastHorizon raises an alert when a network is forecast to be full
+	// within this many days
+	ForecastHorizon float64 `json:"forecast_horizon_days"`
+	// ForecastModel is "linear" or "exponential"
+	ForecastModel string `json:"forecast_model"`
+}
+
+// Keys configures the TUI key bindings
+type Keys struct {
+	// Preset is the base layout: "default", "vim" or "emacs"
+	Preset string `json:"preset"`
+	// Bindings override the keys of individual actions, e.g.
+	// {"create": ["N"]}. An empty list disables the action.
+	Bindings map[string][]string `json:"bindings"`
+}
+
+// fileConfig is the on-disk form of the config file. Every field is optional
+// and overrides the default when present.
+type fileConfig struct {
+	DbFile     *string     `json:"db_file"`
+	DebugFile  *string     `json:"debug_file"`
+	Quarantine *string     `json:"quarantine"`
+	Thresholds *Thresholds `json:"thresholds"`
+	Alerts     *Alerts     `json:"alerts"`
+	Keys       *Keys       `json:"keys"`
+	Theme      *string     `json:"theme"`
+	Mouse      *bool       `json:"mouse"`
 }
 
 func NewConfig() *Config {
 	return &Config{
-		DbFile:    defaultDb,
-		DebugFile: defaultDebugFile,
-		Debug:     false,
+		DbFile:     defaultDb,
+		DebugFile:  defaultDebugFile,
+		Debug:      false,
+		Quarantine: defaultQuarantine,
+		Source:     "cli",
+		Thresholds: Thresholds{
+			Utilisation:   80,
+			Fragmentation: 0.5,
+		},
+		Alerts: Alerts{
+			Log:             true,
+			ForecastHorizon: 30,
+			ForecastModel:   "linear",
+		},
+		Keys:  Keys{Preset: "default"},
+		Theme: "auto",
+		Mouse: true,
+	}
+}
+
+// DefaultPath returns the per-user config file location
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "binip", "config.json")
+}
+
+// Load reads the JSON config file at path over the defaults. A missing file
+// is not an error.
+func Load(path string) (*Config, error) {
+	c := NewConfig()
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Nested sections decode straight over the defaults so omitted keys keep
+	// their default values
+	fc := fileConfig{Thresholds: &c.Thresholds, Alerts: &c.Alerts, Keys: &c.Keys}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("config %s: %s", path, err)
+	}
+	if fc.DbFile != nil {
+		c.DbFile = *fc.DbFile
+	}
+	if fc.DebugFile != nil {
+		c.DebugFile = *fc.DebugFile
+	}
+	if fc.Theme != nil {
+		c.Theme = *fc.Theme
+	}
+	if fc.Mouse != nil {
+		c.Mouse = *fc.Mouse
+	}
+	if fc.Quarantine != nil {
+		if c.Quarantine, err = time.ParseDuration(*fc.Quarantine); err != nil {
+			return nil, fmt.Errorf("config %s: quarantine: %s", path, err)
+		}
 	}
+	return c, nil
 }
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// AllocateRequest describes a bulk allocation
+type AllocateRequest struct {
+	Count int
+	// Contiguous requires one unbroken block of addresses rather than the
+	// first free ones wherever they are
+	Contiguous bool
+	// Hostname is expanded for each record with record.ExpandHostname,
+	// numbering from First
+	Hostname string
+	First    int
+	Role     record.Role
+}
+
+// MaxAllocation is the most addresses a single bulk allocation may assign
+const MaxAllocation = 65536
+
+// allocation is an address a bulk allocation assigns, with the record it
+// replaces if the address is being reused
+type allocation struct {
+	record record.Record
+	old    *record.Record
+}
+
+// freeAddrs calls fn with each address of the network that the allocator may
+// hand out, in order, along with the record held there if any, until fn
+// returns false. Only ranges of the role are searched, or the whole network
+// outside non-allocatable ranges for record.RoleNone.
+func freeAddrs(tx *bolt.Tx, n record.Network, role record.Role, now time.Time, fn func(addr netip.Addr, held *record.Record) bool) error {
+	spans := n.RangesWithRole(role)
+	if role == record.RoleNone {
+		first, last := n.Hosts()
+		spans = []record.Range{{First: first, Last: last}}
+	} else if len(spans) == 0 {
+		return fmt.Errorf("network %s has no %s range", n.Name, role)
+	}
+
+	b := networkRecords(tx, n.Name)
+	for _, span := range spans {
+		for addr := span.First; addr.IsValid() && addr.Compare(span.Last) <= 0; addr = addr.Next() {
+			if n.ProviderReserved(addr) || role == record.RoleNone && !n.RoleOf(addr).Allocatable() {
+				continue
+			}
+			var held *record.Record
+			if b != nil {
+				if v := b.Get([]byte(addr.String())); v != nil {
+					r, err := decodeRecord([]byte(addr.String()), v)
+					if err != nil {
+						return err
+					}
+					if !r.Available(n, now) {
+						continue
+					}
+					held = &r
+				}
+			}
+			if !fn(addr, held) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// exhausted describes a network without enough free addresses
+func exhausted(n record.Network, role record.Role) error {
+	if role != record.RoleNone {
+		return fmt.Errorf("network %s (%s) has no free %s addresses", n.Name, n.Prefix, role)
+	}
+	return fmt.Errorf("network %s (%s) is exhausted", n.Name, n.Prefix)
+}
+
+// newAllocation builds the allocated record for an address, starting afresh
+// rather than inheriting the details of any previous holder
+func newAllocation(n record.Network, addr netip.Addr, held *record.Record, hostname string, now time.Time) (allocation, error) {
+	r := record.Record{Addr: addr, Network: n.Name, Created: now, StateChanged: now}
+	if held != nil {
+		r.Created = held.Created
+	}
+	if err := r.SetState(record.Allocated, n, now); err != nil {
+		return allocation{}, err
+	}
+	r.Hostname = hostname
+	return allocation{record: r, old: held}, nil
+}
+
+// planAllocation picks the addresses and hostnames of a bulk allocation
+func planAllocation(tx *bolt.Tx, network string, req AllocateRequest, now time.Time) ([]allocation, error) {
+	n, err := getNetwork(tx, network)
+	if err != nil {
+		return nil, err
+	}
+	if req.Count < 1 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+	if req.Count > MaxAllocation {
+		return nil, fmt.Errorf("count must be at most %d", MaxAllocation)
+	}
+	first, last := n.Hosts()
+	hosts := new(big.Int).Sub(record.AddrOffset(n.Prefix, last), record.AddrOffset(n.Prefix, first))
+	hosts.Add(hosts, big.NewInt(1))
+	if big.NewInt(int64(req.Count)).Cmp(hosts) > 0 {
+		return nil, fmt.Errorf("network %s (%s) has only %s usable addresses", n.Name, n.Prefix, hosts)
+	}
+	if req.Count > 1 && req.Hostname != "" && !record.HostnameNumbered(req.Hostname) {
+		return nil, fmt.Errorf("hostname template %q needs a {n} placeholder to name %d records", req.Hostname, req.Count)
+	}
+
+	type candidate struct {
+		addr netip.Addr
+		held *record.Record
+	}
+	var picked []candidate
+	err = freeAddrs(tx, n, req.Role, now, func(addr netip.Addr, held *record.Record) bool {
+		if req.Contiguous && len(picked) > 0 && picked[len(picked)-1].addr.Next() != addr {
+			// The run is broken; start a new block here
+			picked = picked[:0]
+		}
+		picked = append(picked, candidate{addr, held})
+		return len(picked) < req.Count
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(picked) < req.Count {
+		if req.Contiguous {
+			return nil, fmt.Errorf("network %s (%s) has no block of %d free addresses", n.Name, n.Prefix, req.Count)
+		}
+		if len(picked) > 0 {
+			return nil, fmt.Errorf("%s: only %d of %d addresses are free", exhausted(n, req.Role), len(picked), req.Count)
+		}
+		return nil, exhausted(n, req.Role)
+	}
+
+	names := indexBucket(tx, network, indexHostname)
+	plan := make([]allocation, len(picked))
+	for i, c := range picked {
+		hostname, err := record.ExpandHostname(req.Hostname, req.First+i)
+		if err != nil {
+			return nil, err
+		}
+		if err := n.CheckHostname(hostname); err != nil {
+			return nil, err
+		}
+		if h := strings.ToLower(strings.TrimSpace(hostname)); h != "" && names != nil {
+			if owner := names.Get([]byte(h)); owner != nil && string(owner) != c.addr.String() {
+				return nil, fmt.Errorf("hostname %q is already used by %s in %s", h, owner, network)
+			}
+		}
+		if plan[i], err = newAllocation(n, c.addr, c.held, hostname, now); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// PlanAllocation returns the records a bulk allocation would assign, without
+// changing anything
+func (db *Db) PlanAllocation(network string, req AllocateRequest) ([]record.Record, error) {
+	var records []record.Record
+	err := db.view(func(tx *bolt.Tx) error {
+		plan, err := planAllocation(tx, network, req, time.Now())
+		for _, a := range plan {
+			records = append(records, a.record)
+		}
+		return err
+	})
+	return records, err
+}
+
+// AllocateMany allocates several addresses in a single transaction: either
+// every address is assigned or none are. It returns the records and the
+// audit entries written.
+func (db *Db) AllocateMany(network string, req AllocateRequest) ([]record.Record, []record.AuditEntry, error) {
+	var records []record.Record
+	entries, err := db.updateAudited(func(tx *bolt.Tx) error {
+		now := time.Now()
+		plan, err := planAllocation(tx, network, req, now)
+		if err != nil {
+			return err
+		}
+		for _, a := range plan {
+			if err := putRecord(tx, a.record); err != nil {
+				return err
+			}
+			if err := db.audit(tx, record.ActionAllocate, network, a.record.ID(), a.old, &a.record, now); err != nil {
+				return err
+			}
+			records = append(records, a.record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return records, entries, nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+func TestPlanAllocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       AllocateRequest
+		want      []string
+		wantNames []string
+		wantErr   string
+	}{
+		{name: "first free", req: AllocateRequest{Count: 3}, want: []string{"10.0.0.1", "10.0.0.3", "10.0.0.4"}},
+		{name: "contiguous", req: AllocateRequest{Count: 3, Contiguous: true}, want: []string{"10.0.0.3", "10.0.0.4", "10.0.0.5"}},
+		{
+			name:      "numbered hostnames",
+			req:       AllocateRequest{Count: 2, Hostname: "node-{n:02}", First: 7},
+			want:      []string{"10.0.0.1", "10.0.0.3"},
+			wantNames: []string{"node-07", "node-08"},
+		},
+		{name: "zero count", req: AllocateRequest{Count: 0}, wantErr: "at least 1"},
+		{name: "unnumbered hostname", req: AllocateRequest{Count: 2, Hostname: "node"}, wantErr: "needs a {n} placeholder"},
+		{name: "too few free", req: AllocateRequest{Count: 5}, wantErr: "only 4 of 5"},
+		{name: "no block", req: AllocateRequest{Count: 4, Contiguous: true}, wantErr: "no block of 4"},
+		{name: "hostname taken", req: AllocateRequest{Count: 2, Hostname: "web{n}", First: 1}, wantErr: `"web2" is already used by 10.0.0.6`},
+		{name: "more than the network holds", req: AllocateRequest{Count: 7}, wantErr: "has only 6 usable addresses"},
+		{name: "over the limit", req: AllocateRequest{Count: 10000000000}, wantErr: "at most 65536"},
+		{name: "missing range", req: AllocateRequest{Count: 1, Role: record.RoleDHCPPool}, wantErr: "no dhcp-pool range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 10.0.0.1-6 are usable; 2 is reserved and 6 is named web2
+			d := newTestDb(t, testNetwork("lab", "10.0.0.0/29"))
+			putTestRecord(t, d, "lab", "10.0.0.2")
+			named := record.Record{Addr: netip.MustParseAddr("10.0.0.6"), Network: "lab", State: record.Reserved, Hostname: "web2"}
+			if _, err := d.PutRecord(named); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := d.PlanAllocation("lab", tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PlanAllocation = %v; want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var addrs, names []string
+			for _, r := range records {
+				addrs = append(addrs, r.ID())
+				names = append(names, r.Hostname)
+				if r.State != record.Allocated {
+					t.Errorf("%s planned as %s; want allocated", r.ID(), r.State)
+				}
+			}
+			if !slices.Equal(addrs, tt.want) {
+				t.Errorf("planned %v; want %v", addrs, tt.want)
+			}
+			if tt.wantNames != nil && !slices.Equal(names, tt.wantNames) {
+				t.Errorf("hostnames %v; want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
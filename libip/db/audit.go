@@ -0,0 +1,345 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Since time.Time
+	// Until only matches entries made at or before this moment
+	Until   time.Time
+	Network string
+	Record  string
+	User    string
+	Source  string
+}
+
+func (f AuditFilter) matches(e record.AuditEntry) bool {
+	return (f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || !e.Time.After(f.Until)) &&
+		(f.Network == "" || e.Network == f.Network) &&
+		(f.Record == "" || e.Record == f.Record) &&
+		(f.User == "" || e.User == f.User) &&
+		(f.Source == "" || e.Source == f.Source)
+}
+
+// ListAudit returns the audit entries matching the filter, oldest first
+func (db *Db) ListAudit(f AuditFilter) ([]record.AuditEntry, error) {
+	var entries []record.AuditEntry
+	err := db.view(func(tx *bolt.Tx) error {
+		return forEachAudit(tx, func(e record.AuditEntry) error {
+			if f.matches(e) {
+				entries = append(entries, e)
+			}
+			return nil
+		})
+	})
+	return entries, err
+}
+
+// forEachAudit calls fn with every audit entry, oldest first
+func forEachAudit(tx *bolt.Tx, fn func(e record.AuditEntry) error) error {
+	return tx.Bucket([]byte(auditBucket)).ForEach(func(k, v []byte) error {
+		var e record.AuditEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return fmt.Errorf("decode audit entry %d: %s", binary.BigEndian.Uint64(k), err)
+		}
+		return fn(e)
+	})
+}
+
+// updateAudited runs fn like update and returns the audit entries it
+// appended. They are read within the same transaction, so entries written by
+// other processes are never included.
+func (db *Db) updateAudited(fn func(tx *bolt.Tx) error) ([]record.AuditEntry, error) {
+	var entries []record.AuditEntry
+	err := db.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(auditBucket))
+		mark := b.Sequence()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		start := make([]byte, 8)
+		binary.BigEndian.PutUint64(start, mark+1)
+		c := b.Cursor()
+		for k, v := c.Seek(start); k != nil; k, v = c.Next() {
+			var e record.AuditEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("decode audit entry %d: %s", binary.BigEndian.Uint64(k), err)
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Undo reverts the record changes of a set of audit entries, newest first, in
+// a single transaction. It fails without changing anything if any record has
+// been changed since.
+func (db *Db) Undo(entries []record.AuditEntry) error {
+	return db.update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if err := db.replay(tx, e, e.After, e.Before, record.ActionUndo, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Redo reapplies the record changes of a set of undone audit entries, oldest
+// first, in a single transaction
+func (db *Db) Redo(entries []record.AuditEntry) error {
+	return db.update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		for _, e := range entries {
+			if err := db.replay(tx, e, e.Before, e.After, record.ActionRedo, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// replay moves a record from the expected state to the target state. Either
+// may be empty, meaning the record does not exist. Lifecycle rules are not
+// applied as the target is a state the record has already been in.
+func (db *Db) replay(tx *bolt.Tx, e record.AuditEntry, expect, target json.RawMessage, action string, now time.Time) error {
+	if e.Record == "" {
+		return fmt.Errorf("%s of network %s cannot be reverted", e.Action, e.Network)
+	}
+	if _, err := getNetwork(tx, e.Network); err != nil {
+		return err
+	}
+	b, err := createNetworkRecords(tx, e.Network)
+	if err != nil {
+		return err
+	}
+	current := b.Get([]byte(e.Record))
+	if !sameState(current, expect) {
+		return fmt.Errorf("conflict: %s has changed since %s", e.Record, e.Action)
+	}
+
+	before := json.RawMessage(bytes.Clone(current))
+	state, put := target, true
+	if len(target) == 0 {
+		state, put = current, false
+	}
+	r, err := decodeRecord([]byte(e.Record), state)
+	if err != nil {
+		return err
+	}
+	if put {
+		err = putRecord(tx, r)
+	} else {
+		err = deleteRecord(tx, r)
+	}
+	if err != nil {
+		return err
+	}
+	return db.audit(tx, action, e.Network, e.Record, before, target, now)
+}
+
+// sameState compares two JSON states ignoring formatting
+func sameState(a, b []byte) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// audit appends an entry to the audit log within the mutating transaction so
+// that the change and its entry are committed together. before and after are
+// nil when the object is created or deleted.
+func (db *Db) audit(tx *bolt.Tx, action, network, id string, before, after any, now time.Time) error {
+	e := record.AuditEntry{
+		Time:    now,
+		User:    db.user,
+		Source:  db.source,
+		Action:  action,
+		Network: network,
+		Record:  id,
+	}
+	var err error
+	if e.Before, err = auditState(before); err != nil {
+		return err
+	}
+	if e.After, err = auditState(after); err != nil {
+		return err
+	}
+
+	b := tx.Bucket([]byte(auditBucket))
+	if e.Seq, err = b.NextSequence(); err != nil {
+		return err
+	}
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, e.Seq)
+	return b.Put(k, v)
+}
+
+func auditState(v any) (json.RawMessage, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		return v, nil
+	case *record.Record:
+		if v == nil {
+			return nil, nil
+		}
+	case *record.Network:
+		if v == nil {
+			return nil, nil
+		}
+	}
+	return json.Marshal(v)
+}
+
+// currentUser returns the OS user running the process
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+	return "unknown"
+}
+
+// rewind walks the audit log back from the newest entry, calling fn with
+// each entry made after at, newest first. Entries are appended in time order,
+// so the walk stops at the first entry made at or before at.
+func rewind(tx *bolt.Tx, at time.Time, fn func(e record.AuditEntry)) error {
+	c := tx.Bucket([]byte(auditBucket)).Cursor()
+	for k, v := c.Last(); k != nil; k, v = c.Prev() {
+		var e record.AuditEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return fmt.Errorf("decode audit entry %d: %s", binary.BigEndian.Uint64(k), err)
+		}
+		if !e.Time.After(at) {
+			return nil
+		}
+		fn(e)
+	}
+	return nil
+}
+
+// RecordsAt reconstructs a network's records as they stood at a past moment
+// by undoing, from the records as they are now, every change made since.
+// Records created since without an audit entry are left out.
+func (db *Db) RecordsAt(network string, at time.Time) ([]record.Record, error) {
+	states := make(map[string]json.RawMessage)
+	err := db.view(func(tx *bolt.Tx) error {
+		if b := networkRecords(tx, network); b != nil {
+			err := b.ForEach(func(k, v []byte) error {
+				states[string(k)] = bytes.Clone(v)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return rewind(tx, at, func(e record.AuditEntry) {
+			if e.Network == network && e.Record != "" {
+				states[e.Record] = e.Before
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []record.Record
+	for id, v := range states {
+		if len(v) == 0 {
+			continue
+		}
+		r, err := decodeRecord([]byte(id), v)
+		if err != nil {
+			return nil, err
+		}
+		if !r.Created.After(at) {
+			records = append(records, r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Addr.Less(records[j].Addr) })
+	return records, nil
+}
+
+// RecordAt returns a single record as it stood at a past moment
+func (db *Db) RecordAt(network, id string, at time.Time) (record.Record, error) {
+	var state json.RawMessage
+	err := db.view(func(tx *bolt.Tx) error {
+		if b := networkRecords(tx, network); b != nil {
+			state = bytes.Clone(b.Get([]byte(id)))
+		}
+		return rewind(tx, at, func(e record.AuditEntry) {
+			if e.Network == network && e.Record == id {
+				state = e.Before
+			}
+		})
+	})
+	if err != nil {
+		return record.Record{}, err
+	}
+	if len(state) > 0 {
+		r, err := decodeRecord([]byte(id), state)
+		if err != nil || !r.Created.After(at) {
+			return r, err
+		}
+	}
+	return record.Record{}, fmt.Errorf("record %s not found in %s at %s", id, network, at.Format(time.RFC3339))
+}
+
+// NetworkAt returns a network's definition as it stood at a past moment, by
+// undoing every change to it made since
+func (db *Db) NetworkAt(name string, at time.Time) (record.Network, error) {
+	var n record.Network
+	var state json.RawMessage
+	err := db.view(func(tx *bolt.Tx) error {
+		state = bytes.Clone(tx.Bucket([]byte(networksBucket)).Get([]byte(name)))
+		return rewind(tx, at, func(e record.AuditEntry) {
+			if e.Network == name && e.Record == "" {
+				state = e.Before
+			}
+		})
+	})
+	if err != nil {
+		return n, err
+	}
+	if len(state) == 0 {
+		return n, fmt.Errorf("network %q did not exist at %s", name, at.Format(time.RFC3339))
+	}
+	if err := json.Unmarshal(state, &n); err != nil {
+		return n, fmt.Errorf("decode network %s: %s", name, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+func TestMutationsReturnTheirAuditEntries(t *testing.T) {
+	d := newTestDb(t, testNetwork("lab", "10.0.0.0/24"))
+	putTestRecord(t, d, "lab", "10.0.0.1")
+
+	r := record.Record{Addr: netip.MustParseAddr("10.0.0.2"), Network: "lab", State: record.Reserved}
+	entries, err := d.PutRecord(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Record != "10.0.0.2" {
+		t.Fatalf("PutRecord returned %v; want the one entry for 10.0.0.2", entries)
+	}
+
+	// Another writer in between must not appear in a later change
+	putTestRecord(t, d, "lab", "10.0.0.3")
+	entries, err = d.DeleteRecord("lab", "10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != record.ActionDelete {
+		t.Fatalf("DeleteRecord returned %v; want the one delete entry", entries)
+	}
+	if err := d.Undo(entries); err != nil {
+		t.Fatalf("undo: %s", err)
+	}
+	if _, err := d.GetRecord("lab", "10.0.0.2"); err != nil {
+		t.Errorf("undo did not restore 10.0.0.2: %s", err)
+	}
+	if _, err := d.GetRecord("lab", "10.0.0.3"); err != nil {
+		t.Errorf("undo removed a change it did not make: %s", err)
+	}
+}
+
+func TestRecordsAt(t *testing.T) {
+	d := newTestDb(t, testNetwork("lab", "10.0.0.0/24"))
+	putTestRecord(t, d, "lab", "10.0.0.1")
+	putTestRecord(t, d, "lab", "10.0.0.2")
+	at := time.Now()
+
+	r, err := d.GetRecord("lab", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Owner = "ops"
+	if _, err := d.PutRecord(r); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DeleteRecord("lab", "10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	putTestRecord(t, d, "lab", "10.0.0.3")
+
+	records, err := d.RecordsAt("lab", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range records {
+		ids = append(ids, r.ID())
+	}
+	if !slices.Equal(ids, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("RecordsAt = %v; want 10.0.0.1, 10.0.0.2", ids)
+	}
+	if got, err := d.RecordAt("lab", "10.0.0.1", at); err != nil || got.Owner != "" {
+		t.Errorf("RecordAt 10.0.0.1 = owner %q, %v; want the record before its edit", got.Owner, err)
+	}
+	if _, err := d.RecordAt("lab", "10.0.0.3", at); err == nil {
+		t.Error("RecordAt found 10.0.0.3 before it was created")
+	}
+	history, err := d.ListAudit(AuditFilter{Network: "lab", Record: "10.0.0.1", Until: at})
+	if err != nil || len(history) != 1 {
+		t.Errorf("history until %s = %d entries, %v; want only the create", at, len(history), err)
+	}
+}
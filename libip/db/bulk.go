@@ -0,0 +1,180 @@
+package db
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BulkChange is one edit applied to many records. Parts left empty are not
+// changed.
+type BulkChange struct {
+	// Fields sets custom fields; an empty value removes the field
+	Fields     map[string]string
+	AddTags    []string
+	RemoveTags []string
+	// State moves every record to this lifecycle state; quarantined releases
+	// them
+	State *record.State
+	// MoveTo names a network to move the records to, each at the same offset
+	// from the start of the prefix as it had in its own network
+	MoveTo string
+}
+
+// String summarises the change, e.g. "add tags k8s; state reserved"
+func (c BulkChange) String() string {
+	var parts []string
+	for _, name := range slices.Sorted(maps.Keys(c.Fields)) {
+		if v := c.Fields[name]; v != "" {
+			parts = append(parts, fmt.Sprintf("set %s=%s", name, v))
+		} else {
+			parts = append(parts, "clear "+name)
+		}
+	}
+	if len(c.AddTags) > 0 {
+		parts = append(parts, "add tags "+strings.Join(c.AddTags, ", "))
+	}
+	if len(c.RemoveTags) > 0 {
+		parts = append(parts, "remove tags "+strings.Join(c.RemoveTags, ", "))
+	}
+	if c.State != nil {
+		if *c.State == record.Quarantined {
+			parts = append(parts, "release")
+		} else {
+			parts = append(parts, "state "+c.State.String())
+		}
+	}
+	if c.MoveTo != "" {
+		parts = append(parts, "move to "+c.MoveTo)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// BulkUpdate applies a change to records of a network in a single
+// transaction; if any record is rejected none are changed. progress, when
+// set, is called with the number of records done so far. It returns the
+// audit entries written.
+func (db *Db) BulkUpdate(network string, ids []string, c BulkChange, progress func(done int)) ([]record.AuditEntry, error) {
+	c.AddTags, c.RemoveTags = record.NormaliseTags(c.AddTags), record.NormaliseTags(c.RemoveTags)
+	return db.updateAudited(func(tx *bolt.Tx) error {
+		n, err := getNetwork(tx, network)
+		if err != nil {
+			return err
+		}
+		var target record.Network
+		if c.MoveTo != "" {
+			if c.MoveTo == network {
+				return fmt.Errorf("records are already in %s", network)
+			}
+			if target, err = getNetwork(tx, c.MoveTo); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		for i, id := range ids {
+			if err := db.bulkUpdateRecord(tx, n, target, id, c, now); err != nil {
+				return fmt.Errorf("%s: %s", id, err)
+			}
+			if progress != nil {
+				progress(i + 1)
+			}
+		}
+		return nil
+	})
+}
+
+// bulkUpdateRecord applies a bulk change to one record. target is the
+// network to move it to, if any.
+func (db *Db) bulkUpdateRecord(tx *bolt.Tx, n, target record.Network, id string, c BulkChange, now time.Time) error {
+	r, err := getRecord(tx, n.Name, id)
+	if err != nil {
+		return err
+	}
+	old := r
+	r.Fields = maps.Clone(r.Fields)
+	for name, v := range c.Fields {
+		r.SetField(name, v)
+	}
+	r.Tags = slices.DeleteFunc(append(slices.Clone(r.Tags), c.AddTags...), func(t string) bool {
+		return slices.Contains(c.RemoveTags, t)
+	})
+	r.Tags = record.NormaliseTags(r.Tags)
+
+	action := record.ActionUpdate
+	if c.State != nil && *c.State != r.State {
+		if err := r.SetState(*c.State, n, now); err != nil {
+			return err
+		}
+		if r.Equal(withState(old, r)) {
+			// Only the state changed
+			action = record.ActionState
+			if r.State == record.Quarantined {
+				action = record.ActionRelease
+			}
+		}
+	}
+
+	if c.MoveTo != "" {
+		return db.moveRecord(tx, old, r, n, target, now)
+	}
+	if r.Equal(old) {
+		return nil
+	}
+	if err := n.ValidateRecord(r); err != nil {
+		return err
+	}
+	r.Updated = now
+	if err := putRecord(tx, r); err != nil {
+		return err
+	}
+	return db.audit(tx, action, n.Name, id, &old, &r, now)
+}
+
+// withState returns r with the lifecycle state of s
+func withState(r, s record.Record) record.Record {
+	r.State, r.StateChanged, r.Updated = s.State, s.StateChanged, s.Updated
+	return r
+}
+
+// moveRecord moves a record from network n to the same offset in target,
+// recording its removal from n and its creation in target
+func (db *Db) moveRecord(tx *bolt.Tx, old, r record.Record, n, target record.Network, now time.Time) error {
+	if n.Prefix.Addr().Is4() != target.Prefix.Addr().Is4() {
+		return fmt.Errorf("cannot move between %s and %s: address families differ", n.Prefix, target.Prefix)
+	}
+	off := record.AddrOffset(n.Prefix, r.Addr)
+	first, last := target.Hosts()
+	if off.Cmp(record.AddrOffset(target.Prefix, first)) < 0 || off.Cmp(record.AddrOffset(target.Prefix, last)) > 0 {
+		return fmt.Errorf("offset %s lies outside the hosts of %s (%s)", off, target.Name, target.Prefix)
+	}
+	addr, ok := record.AddrAt(target.Prefix, off)
+	if !ok {
+		return fmt.Errorf("offset %s lies outside %s (%s)", off, target.Name, target.Prefix)
+	}
+	if _, err := getRecord(tx, target.Name, addr.String()); err == nil {
+		return fmt.Errorf("%s is already recorded in %s", addr, target.Name)
+	}
+	r.Addr, r.Network, r.Updated = addr, target.Name, now
+	if err := target.ValidateRecord(r); err != nil {
+		return err
+	}
+	if err := target.CheckHostname(r.Hostname); err != nil {
+		return err
+	}
+	if err := deleteRecord(tx, old); err != nil {
+		return err
+	}
+	if err := db.audit(tx, record.ActionMove, n.Name, old.ID(), &old, nil, now); err != nil {
+		return err
+	}
+	if err := putRecord(tx, r); err != nil {
+		return err
+	}
+	return db.audit(tx, record.ActionMove, target.Name, r.ID(), nil, &r, now)
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBulkUpdateMove(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		addr    string
+		to      string
+		want    string
+		wantErr string
+	}{
+		{name: "same offset", from: "10.0.0.0/24", addr: "10.0.0.5", to: "10.1.0.0/24", want: "10.1.0.5"},
+		{name: "into larger prefix", from: "10.0.0.0/24", addr: "10.0.0.200", to: "10.2.0.0/16", want: "10.2.0.200"},
+		{name: "offset past target", from: "10.0.0.0/16", addr: "10.0.1.5", to: "10.1.0.0/24", wantErr: "outside the hosts"},
+		{name: "target broadcast", from: "10.0.0.0/16", addr: "10.0.0.255", to: "10.1.0.0/24", wantErr: "outside the hosts"},
+		{name: "cross family", from: "fd00::/64", addr: "fd00::5", to: "10.1.0.0/24", wantErr: "address families differ"},
+		{name: "oversized offset", from: "fd00::/64", addr: "fd00::ffff:ffff:ffff", to: "fd01::/120", wantErr: "outside the hosts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDb(t, testNetwork("src", tt.from), testNetwork("dst", tt.to))
+			putTestRecord(t, d, "src", tt.addr)
+
+			_, err := d.BulkUpdate("src", []string{tt.addr}, BulkChange{MoveTo: "dst"}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := d.GetRecord("src", tt.addr); err != nil {
+					t.Errorf("rejected move removed the record: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := d.GetRecord("dst", tt.want); err != nil {
+				t.Errorf("moved record: %s", err)
+			}
+			if _, err := d.GetRecord("src", tt.addr); err == nil {
+				t.Errorf("%s is still in src", tt.addr)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateTagsNormalised(t *testing.T) {
+	d := newTestDb(t, testNetwork("lab", "10.0.0.0/24"))
+	putTestRecord(t, d, "lab", "10.0.0.1")
+
+	if _, err := d.BulkUpdate("lab", []string{"10.0.0.1"}, BulkChange{AddTags: []string{" K8s ", "Web"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.BulkUpdate("lab", []string{"10.0.0.1"}, BulkChange{RemoveTags: []string{"K8S"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	r, err := d.GetRecord("lab", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(r.Tags, []string{"web"}) {
+		t.Errorf("tags = %v; want [web]", r.Tags)
+	}
+}
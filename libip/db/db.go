@@ -1,10 +1,14 @@
 package db
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net/netip"
+	"sync"
+	"time"
 
 	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/record"
 	"github.com/bakedSpaceTime/binip/libip/styles"
 	"github.com/charmbracelet/lipgloss"
 	bolt "go.etcd.io/bbolt"
@@ -12,14 +16,42 @@ import (
 
 const (
 	ipRecordsBucket = "ip_records"
+	networksBucket  = "networks"
+	templatesBucket = "templates"
+	historyBucket   = "history"
+	alertsBucket    = "alerts"
+	auditBucket     = "audit"
+	searchesBucket  = "searches"
+	sessionsBucket  = "sessions"
+	indexesBucket   = "indexes"
 	systemBucket    = "system"
 	version         = "0.1.0"
 	cidrBlockKey    = "cidr_block"
+	revisionKey     = "revision"
+	// lockTimeout is how long a shared database waits for another process
+	// to release the file
+	lockTimeout = 5 * time.Second
 )
 
+// buckets are the top-level buckets created on open and removed on reset;
+// templates and indexes are handled separately so they are seeded or built
+// only once
+var buckets = []string{ipRecordsBucket, networksBucket, historyBucket, alertsBucket, auditBucket, searchesBucket, sessionsBucket, systemBucket}
+
 type Db struct {
-	Db     *bolt.DB
-	dbFile string
+	Db         *bolt.DB
+	dbFile     string
+	quarantine time.Duration
+	// user and source are recorded against every audited change
+	user   string
+	source string
+
+	// A shared database holds the file open only while transactions run,
+	// so that other processes can write in between; users counts the
+	// transactions running
+	shared bool
+	mu     sync.Mutex
+	users  int
 }
 
 func New(c *config.Config) *Db {
@@ -29,40 +61,150 @@ func New(c *config.Config) *Db {
 	}
 
 	db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(ipRecordsBucket))
-		if err != nil {
-			return fmt.Errorf("create bucket: %s", err)
+		for _, name := range buckets {
+			_, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
 		}
-		_, err = tx.CreateBucketIfNotExists([]byte(systemBucket))
-		if err != nil {
-			return fmt.Errorf("create bucket: %s", err)
+		if tx.Bucket([]byte(templatesBucket)) == nil {
+			_, err := tx.CreateBucket([]byte(templatesBucket))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+			if err = seedTemplates(tx); err != nil {
+				return err
+			}
+		}
+
+		if tx.Bucket([]byte(indexesBucket)) == nil {
+			if _, err := rebuildIndexes(tx); err != nil {
+				return err
+			}
 		}
 
 		b := tx.Bucket([]byte(systemBucket))
-		err = b.Put([]byte("version"), []byte(version))
+		err := b.Put([]byte("version"), []byte(version))
 		err = b.Put([]byte("app_name"), []byte("binip"))
 
 		return err
 	})
 
+	u := c.User
+	if u == "" {
+		u = currentUser()
+	}
 	return &Db{
-		Db: db,
+		Db:         db,
+		dbFile:     c.DbFile,
+		quarantine: c.Quarantine,
+		user:       u,
+		source:     c.Source,
 	}
 }
 
+// NewShared opens the database like New but releases the file between
+// transactions, for long-running sessions such as the TUI
+func NewShared(c *config.Config) *Db {
+	db := New(c)
+	db.Db.Close()
+	db.Db, db.shared = nil, true
+	return db
+}
+
 func (db *Db) Close() error {
+	if db.shared {
+		return nil
+	}
 	return db.Db.Close()
 }
 
-func (db *Db) Reset() error {
-	return db.Db.Update(func(tx *bolt.Tx) error {
-		err := tx.DeleteBucket([]byte(ipRecordsBucket))
+// acquire opens a shared database for a transaction
+func (db *Db) acquire() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.shared && db.users == 0 {
+		b, err := bolt.Open(db.dbFile, 0600, &bolt.Options{Timeout: lockTimeout})
 		if err != nil {
-			return fmt.Errorf("delete bucket: %s", err)
+			return fmt.Errorf("open %s: %s", db.dbFile, err)
 		}
-		err = tx.DeleteBucket([]byte(systemBucket))
-		if err != nil {
-			return fmt.Errorf("delete bucket: %s", err)
+		db.Db = b
+	}
+	db.users++
+	return nil
+}
+
+// release closes a shared database once no transaction is running
+func (db *Db) release() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.users--
+	if db.shared && db.users == 0 {
+		db.Db.Close()
+		db.Db = nil
+	}
+}
+
+// view runs fn in a read-only transaction
+func (db *Db) view(fn func(tx *bolt.Tx) error) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	return db.Db.View(fn)
+}
+
+// update runs fn in a read-write transaction and bumps the revision, so that
+// other processes can tell the database has changed
+func (db *Db) update(fn func(tx *bolt.Tx) error) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	return db.Db.Update(func(tx *bolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		b := tx.Bucket([]byte(systemBucket))
+		if b == nil {
+			// Reset has removed it
+			return nil
+		}
+		next := make([]byte, 8)
+		if v := b.Get([]byte(revisionKey)); len(v) == 8 {
+			binary.BigEndian.PutUint64(next, binary.BigEndian.Uint64(v)+1)
+		} else {
+			binary.BigEndian.PutUint64(next, 1)
+		}
+		return b.Put([]byte(revisionKey), next)
+	})
+}
+
+// Revision returns a counter that is bumped by every change to the database.
+// It is 0 once the database has been reset.
+func (db *Db) Revision() (uint64, error) {
+	var rev uint64
+	err := db.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(systemBucket))
+		if b == nil {
+			// Reset has removed it
+			return nil
+		}
+		if v := b.Get([]byte(revisionKey)); len(v) == 8 {
+			rev = binary.BigEndian.Uint64(v)
+		}
+		return nil
+	})
+	return rev, err
+}
+
+func (db *Db) Reset() error {
+	return db.update(func(tx *bolt.Tx) error {
+		for _, name := range append(buckets, templatesBucket, indexesBucket) {
+			err := tx.DeleteBucket([]byte(name))
+			if err != nil {
+				return fmt.Errorf("delete bucket: %s", err)
+			}
 		}
 		return nil
 	})
@@ -71,7 +213,7 @@ func (db *Db) Reset() error {
 func (db *Db) String() string {
 	bs := make(map[string][][]string)
 
-	db.Db.View(
+	db.view(
 		func(tx *bolt.Tx) error {
 			tx.ForEach(
 				func(name []byte, b *bolt.Bucket) error {
@@ -98,17 +240,42 @@ func (db *Db) String() string {
 	return lipgloss.JoinVertical(lipgloss.Top, components...)
 }
 
-func (db *Db) SetNetwork(prefix string) error {
-	return db.Db.Update(func(tx *bolt.Tx) error {
+// SetNetwork stores the onboarding prefix and creates the default network for
+// it, applying the named template if one is given
+func (db *Db) SetNetwork(prefix, template string) error {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	return db.update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(indexesBucket)) == nil {
+			if _, err := rebuildIndexes(tx); err != nil {
+				return err
+			}
+		}
+
 		b := tx.Bucket([]byte(systemBucket))
 		err := b.Put([]byte(cidrBlockKey), []byte(prefix))
-		return err
+		if err != nil {
+			return err
+		}
+		old, err := getNetwork(tx, record.DefaultNetwork)
+		if err != nil {
+			n := record.Network{Name: record.DefaultNetwork, Prefix: p.Masked(), Quarantine: db.quarantine}
+			return db.createNetwork(tx, n, template)
+		}
+		n := old
+		n.Prefix = p.Masked()
+		if err := n.Validate(); err != nil {
+			return err
+		}
+		return db.updateNetwork(tx, &old, n)
 	})
 }
 
 func (db *Db) GetNetwork() (string, error) {
 	var prefix string
-	err := db.Db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(systemBucket))
 		v := b.Get([]byte(cidrBlockKey))
 		if v != nil {
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+// newTestDb opens an empty database in a temporary directory with the given
+// networks
+func newTestDb(t *testing.T, networks ...record.Network) *Db {
+	t.Helper()
+	c := config.NewConfig()
+	c.DbFile = filepath.Join(t.TempDir(), "test.db")
+	c.User = "test"
+	d := New(c)
+	t.Cleanup(func() { d.Close() })
+	for _, n := range networks {
+		if err := d.SaveNetwork(n); err != nil {
+			t.Fatalf("save network %s: %s", n.Name, err)
+		}
+	}
+	return d
+}
+
+// testNetwork is a network with no ranges or policies
+func testNetwork(name, prefix string) record.Network {
+	return record.Network{Name: name, Prefix: netip.MustParsePrefix(prefix)}
+}
+
+// putTestRecord stores a reserved record
+func putTestRecord(t *testing.T, d *Db, network, addr string) {
+	t.Helper()
+	r := record.Record{Addr: netip.MustParseAddr(addr), Network: network, State: record.Reserved}
+	if _, err := d.PutRecord(r); err != nil {
+		t.Fatalf("put %s: %s", addr, err)
+	}
+}
+
+func TestRevisionAfterReset(t *testing.T) {
+	c := config.NewConfig()
+	c.DbFile = filepath.Join(t.TempDir(), "test.db")
+	c.User = "test"
+	tui, cli := NewShared(c), NewShared(c)
+	if err := cli.SaveNetwork(testNetwork("lab", "10.0.0.0/24")); err != nil {
+		t.Fatal(err)
+	}
+	if rev, err := tui.Revision(); err != nil || rev == 0 {
+		t.Fatalf("Revision = %d, %v; want a change counted", rev, err)
+	}
+
+	if err := cli.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if rev, err := tui.Revision(); err != nil || rev != 0 {
+		t.Errorf("Revision after reset = %d, %v; want 0", rev, err)
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// sampleKeyLayout keys history samples by day so that one sample is kept per
+// network per day and keys sort chronologically
+const sampleKeyLayout = "2006-01-02"
+
+// PutSample stores the utilisation sample for its day, replacing any earlier
+// sample from the same day
+func (db *Db) PutSample(network string, s record.Sample) error {
+	return db.update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket([]byte(historyBucket)).CreateBucketIfNotExists([]byte(network))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		s.Date = s.Date.UTC().Truncate(24 * time.Hour)
+		v, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(s.Date.Format(sampleKeyLayout)), v)
+	})
+}
+
+// ListSamples returns a network's utilisation history, oldest first
+func (db *Db) ListSamples(network string) ([]record.Sample, error) {
+	var samples []record.Sample
+	err := db.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket)).Bucket([]byte(network))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var s record.Sample
+			if err := json.Unmarshal(v, &s); err != nil {
+				return fmt.Errorf("decode sample %s/%s: %s", network, k, err)
+			}
+			samples = append(samples, s)
+			return nil
+		})
+	})
+	return samples, err
+}
+
+// AlertState returns which alerts are currently raised for a network, so that
+// alerts fire once when a threshold is crossed rather than on every check
+func (db *Db) AlertState(network string) (map[string]bool, error) {
+	state := make(map[string]bool)
+	err := db.view(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(alertsBucket)).Get([]byte(network))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &state)
+	})
+	return state, err
+}
+
+// SetAlertState records which alerts are raised for a network
+func (db *Db) SetAlertState(network string, state map[string]bool) error {
+	return db.update(func(tx *bolt.Tx) error {
+		v, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(alertsBucket)).Put([]byte(network), v)
+	})
+}
@@ -0,0 +1,294 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Secondary indexes map a field value to the address holding it, per network:
+// indexes/<network>/<index>/<value> = <addr>. Tags are not unique, so the tag
+// index is keyed by <tag>\x00<addr> and read by prefix.
+const (
+	indexHostname = "hostname"
+	indexMAC      = "mac"
+	indexTag      = "tag"
+)
+
+var indexNames = []string{indexHostname, indexMAC, indexTag}
+
+// indexEntry is a key a record contributes to one index
+type indexEntry struct {
+	index  string
+	key    string
+	unique bool
+}
+
+// indexEntries returns the normalised index keys of a record. Empty fields
+// are not indexed.
+func indexEntries(r record.Record) []indexEntry {
+	var entries []indexEntry
+	if h := strings.ToLower(strings.TrimSpace(r.Hostname)); h != "" {
+		entries = append(entries, indexEntry{indexHostname, h, true})
+	}
+	if m := normaliseMAC(r.MAC); m != "" {
+		entries = append(entries, indexEntry{indexMAC, m, true})
+	}
+	for _, t := range r.Tags {
+		entries = append(entries, indexEntry{indexTag, t + "\x00" + r.ID(), false})
+	}
+	return entries
+}
+
+// displayKey renders an index key for messages
+func displayKey(key string) string {
+	return strings.ReplaceAll(key, "\x00", " ")
+}
+
+// normaliseMAC returns a MAC address in canonical lower-case colon form so
+// that differently written forms of the same address collide
+func normaliseMAC(s string) string {
+	s = strings.TrimSpace(s)
+	if hw, err := net.ParseMAC(s); err == nil {
+		return hw.String()
+	}
+	return strings.ToLower(s)
+}
+
+func indexBucket(tx *bolt.Tx, network, index string) *bolt.Bucket {
+	nb := tx.Bucket([]byte(indexesBucket)).Bucket([]byte(network))
+	if nb == nil {
+		return nil
+	}
+	return nb.Bucket([]byte(index))
+}
+
+func createIndexBucket(tx *bolt.Tx, network, index string) (*bolt.Bucket, error) {
+	nb, err := tx.Bucket([]byte(indexesBucket)).CreateBucketIfNotExists([]byte(network))
+	if err != nil {
+		return nil, fmt.Errorf("create bucket: %s", err)
+	}
+	b, err := nb.CreateBucketIfNotExists([]byte(index))
+	if err != nil {
+		return nil, fmt.Errorf("create bucket: %s", err)
+	}
+	return b, nil
+}
+
+// indexRecord adds a record's values to the indexes, refusing unique values
+// already held by another address of the network
+func indexRecord(tx *bolt.Tx, r record.Record) error {
+	for _, e := range indexEntries(r) {
+		b, err := createIndexBucket(tx, r.Network, e.index)
+		if err != nil {
+			return err
+		}
+		if owner := b.Get([]byte(e.key)); e.unique && owner != nil && string(owner) != r.ID() {
+			return fmt.Errorf("%s %q is already used by %s in %s", e.index, e.key, owner, r.Network)
+		}
+		if err := b.Put([]byte(e.key), []byte(r.ID())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexRecord removes a record's values from the indexes
+func unindexRecord(tx *bolt.Tx, r record.Record) error {
+	for _, e := range indexEntries(r) {
+		b := indexBucket(tx, r.Network, e.index)
+		if b == nil || string(b.Get([]byte(e.key))) != r.ID() {
+			continue
+		}
+		if err := b.Delete([]byte(e.key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns the record indexed under a value
+func (db *Db) lookup(network, index, key string) (record.Record, error) {
+	var r record.Record
+	err := db.view(func(tx *bolt.Tx) error {
+		b := indexBucket(tx, network, index)
+		var id []byte
+		if b != nil {
+			id = b.Get([]byte(key))
+		}
+		if id == nil {
+			return fmt.Errorf("no record with %s %q in %s", index, key, network)
+		}
+		var err error
+		r, err = getRecord(tx, network, string(id))
+		return err
+	})
+	return r, err
+}
+
+// LookupHostname returns the record of a network holding a hostname
+func (db *Db) LookupHostname(network, hostname string) (record.Record, error) {
+	return db.lookup(network, indexHostname, strings.ToLower(strings.TrimSpace(hostname)))
+}
+
+// LookupMAC returns the record of a network holding a MAC address
+func (db *Db) LookupMAC(network, mac string) (record.Record, error) {
+	return db.lookup(network, indexMAC, normaliseMAC(mac))
+}
+
+// LookupTag returns the records of a network carrying a tag, in address order
+func (db *Db) LookupTag(network, tag string) ([]record.Record, error) {
+	var records []record.Record
+	err := db.view(func(tx *bolt.Tx) error {
+		b := indexBucket(tx, network, indexTag)
+		if b == nil {
+			return nil
+		}
+		prefix := []byte(strings.ToLower(tag) + "\x00")
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			r, err := getRecord(tx, network, string(v))
+			if err != nil {
+				return err
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	sort.Slice(records, func(i, j int) bool { return records[i].Addr.Less(records[j].Addr) })
+	return records, err
+}
+
+// CheckIndexes compares the indexes with the records they are built from and
+// describes every discrepancy
+func (db *Db) CheckIndexes() ([]string, error) {
+	var problems []string
+	err := db.view(func(tx *bolt.Tx) error {
+		want, err := expectedIndexes(tx)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, want.problems...)
+		err = tx.Bucket([]byte(indexesBucket)).ForEach(func(network, _ []byte) error {
+			for _, index := range indexNames {
+				b := indexBucket(tx, string(network), index)
+				if b == nil {
+					continue
+				}
+				err := b.ForEach(func(k, v []byte) error {
+					path := indexKey{string(network), index, string(k)}
+					got, ok := want.entries[path]
+					switch {
+					case !ok:
+						problems = append(problems, fmt.Sprintf("%s: stale entry for %s", path, v))
+					case got != string(v):
+						problems = append(problems, fmt.Sprintf("%s: points at %s, want %s", path, v, got))
+					}
+					delete(want.entries, path)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		for path, id := range want.entries {
+			problems = append(problems, fmt.Sprintf("%s: missing entry for %s", path, id))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// RebuildIndexes discards and rebuilds every secondary index from the
+// records. Duplicate values are reported and left unindexed.
+func (db *Db) RebuildIndexes() ([]string, error) {
+	var problems []string
+	err := db.update(func(tx *bolt.Tx) error {
+		var err error
+		problems, err = rebuildIndexes(tx)
+		return err
+	})
+	return problems, err
+}
+
+func rebuildIndexes(tx *bolt.Tx) ([]string, error) {
+	if tx.Bucket([]byte(indexesBucket)) != nil {
+		if err := tx.DeleteBucket([]byte(indexesBucket)); err != nil {
+			return nil, fmt.Errorf("delete bucket: %s", err)
+		}
+	}
+	if _, err := tx.CreateBucket([]byte(indexesBucket)); err != nil {
+		return nil, fmt.Errorf("create bucket: %s", err)
+	}
+	want, err := expectedIndexes(tx)
+	if err != nil {
+		return nil, err
+	}
+	for path, id := range want.entries {
+		b, err := createIndexBucket(tx, path.network, path.index)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Put([]byte(path.key), []byte(id)); err != nil {
+			return nil, err
+		}
+	}
+	return want.problems, nil
+}
+
+// indexKey locates an entry of the indexes. Network names may contain any
+// character, so the parts are kept apart rather than joined into a path.
+type indexKey struct {
+	network string
+	index   string
+	key     string
+}
+
+// String renders the key as network/index/value for messages
+func (k indexKey) String() string {
+	return fmt.Sprintf("%s/%s/%s", k.network, k.index, displayKey(k.key))
+}
+
+// indexState is the index content implied by the records, along with any
+// duplicate values found
+type indexState struct {
+	entries  map[indexKey]string
+	problems []string
+}
+
+func expectedIndexes(tx *bolt.Tx) (indexState, error) {
+	s := indexState{entries: make(map[indexKey]string)}
+	err := tx.Bucket([]byte(ipRecordsBucket)).ForEach(func(network, _ []byte) error {
+		b := networkRecords(tx, string(network))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			r, err := decodeRecord(k, v)
+			if err != nil {
+				return err
+			}
+			for _, e := range indexEntries(r) {
+				path := indexKey{string(network), e.index, e.key}
+				if other, ok := s.entries[path]; ok {
+					s.problems = append(s.problems, fmt.Sprintf("%s: duplicate %s %q on %s and %s", network, e.index, e.key, other, r.ID()))
+					continue
+				}
+				s.entries[path] = r.ID()
+			}
+			return nil
+		})
+	})
+	return s, err
+}
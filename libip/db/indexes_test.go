@@ -0,0 +1,27 @@
+package db
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+func TestRebuildIndexesSlashInNetworkName(t *testing.T) {
+	d := newTestDb(t, testNetwork("dc1/lab", "10.0.0.0/24"))
+	r := record.Record{Addr: netip.MustParseAddr("10.0.0.5"), Network: "dc1/lab", State: record.Reserved, Hostname: "web/1"}
+	if _, err := d.PutRecord(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if problems, err := d.RebuildIndexes(); err != nil || len(problems) > 0 {
+		t.Fatalf("RebuildIndexes = %v, %v", problems, err)
+	}
+	got, err := d.LookupHostname("dc1/lab", "web/1")
+	if err != nil || got.ID() != "10.0.0.5" {
+		t.Errorf("LookupHostname after rebuild = %s, %v; want 10.0.0.5", got.ID(), err)
+	}
+	if problems, err := d.CheckIndexes(); err != nil || len(problems) > 0 {
+		t.Errorf("CheckIndexes after rebuild = %v, %v", problems, err)
+	}
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SaveNetwork creates or replaces a network definition
+func (db *Db) SaveNetwork(n record.Network) error {
+	if n.Name == "" {
+		return fmt.Errorf("network name required")
+	}
+	if !n.Prefix.IsValid() {
+		return fmt.Errorf("network %s: invalid prefix", n.Name)
+	}
+	n.Prefix = n.Prefix.Masked()
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	return db.update(func(tx *bolt.Tx) error {
+		old, err := getNetwork(tx, n.Name)
+		if err != nil {
+			if err := putNetwork(tx, n); err != nil {
+				return err
+			}
+			return db.audit(tx, record.ActionNetworkCreate, n.Name, "", nil, &n, time.Now())
+		}
+		return db.updateNetwork(tx, &old, n)
+	})
+}
+
+// updateNetwork replaces a stored network and audits the change. A new
+// prefix must still hold every record of the network.
+func (db *Db) updateNetwork(tx *bolt.Tx, old *record.Network, n record.Network) error {
+	if n.Prefix != old.Prefix {
+		if err := checkRecordsWithin(tx, n); err != nil {
+			return err
+		}
+	}
+	if err := putNetwork(tx, n); err != nil {
+		return err
+	}
+	return db.audit(tx, record.ActionNetworkUpdate, n.Name, "", old, &n, time.Now())
+}
+
+// checkRecordsWithin fails if any record of the network lies outside its
+// prefix
+func checkRecordsWithin(tx *bolt.Tx, n record.Network) error {
+	b := networkRecords(tx, n.Name)
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, _ []byte) error {
+		addr, err := netip.ParseAddr(string(k))
+		if err != nil {
+			return err
+		}
+		if !n.Prefix.Contains(addr) {
+			return fmt.Errorf("network %s: record %s is outside %s", n.Name, addr, n.Prefix)
+		}
+		return nil
+	})
+}
+
+// RemoveNetworkField drops a custom field from a network along with the
+// values its records hold for it
+func (db *Db) RemoveNetworkField(network, name string) error {
+	return db.update(func(tx *bolt.Tx) error {
+		old, err := getNetwork(tx, network)
+		if err != nil {
+			return err
+		}
+		n := old
+		n.Fields = slices.DeleteFunc(slices.Clone(old.Fields), func(f record.FieldDef) bool { return f.Name == name })
+		if len(n.Fields) == len(old.Fields) {
+			return fmt.Errorf("network %s has no field %q", network, name)
+		}
+		if err := db.updateNetwork(tx, &old, n); err != nil {
+			return err
+		}
+
+		b := networkRecords(tx, network)
+		if b == nil {
+			return nil
+		}
+		var holders []record.Record
+		err = b.ForEach(func(k, v []byte) error {
+			r, err := decodeRecord(k, v)
+			if err == nil && r.Fields[name] != "" {
+				holders = append(holders, r)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, prev := range holders {
+			r := prev
+			r.Fields = maps.Clone(prev.Fields)
+			r.SetField(name, "")
+			r.Updated = now
+			if err := putRecord(tx, r); err != nil {
+				return err
+			}
+			if err := db.audit(tx, record.ActionUpdate, network, r.ID(), &prev, &r, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetNetworkByName returns the named network
+func (db *Db) GetNetworkByName(name string) (record.Network, error) {
+	var n record.Network
+	err := db.view(func(tx *bolt.Tx) error {
+		var err error
+		n, err = getNetwork(tx, name)
+		return err
+	})
+	return n, err
+}
+
+// ListNetworks returns every network ordered by name
+func (db *Db) ListNetworks() ([]record.Network, error) {
+	var networks []record.Network
+	err := db.view(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(networksBucket)).ForEach(func(k, v []byte) error {
+			var n record.Network
+			if err := json.Unmarshal(v, &n); err != nil {
+				return fmt.Errorf("decode network %s: %s", k, err)
+			}
+			networks = append(networks, n)
+			return nil
+		})
+	})
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks, err
+}
+
+func getNetwork(tx *bolt.Tx, name string) (record.Network, error) {
+	var n record.Network
+	v := tx.Bucket([]byte(networksBucket)).Get([]byte(name))
+	if v == nil {
+		return n, fmt.Errorf("network %q not found", name)
+	}
+	if err := json.Unmarshal(v, &n); err != nil {
+		return n, fmt.Errorf("decode network %s: %s", name, err)
+	}
+	return n, nil
+}
+
+func putNetwork(tx *bolt.Tx, n record.Network) error {
+	v, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(networksBucket)).Put([]byte(n.Name), v)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+func TestSetNetworkPrefixChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		wantErr string
+	}{
+		{name: "wider", prefix: "10.0.0.0/16"},
+		{name: "still holds everything", prefix: "10.0.0.0/25"},
+		{name: "record outside", prefix: "10.0.0.0/26", wantErr: "record 10.0.0.100 is outside"},
+		{name: "range outside", prefix: "10.0.0.0/28", wantErr: "range dhcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := testNetwork(record.DefaultNetwork, "10.0.0.0/24")
+			n.Ranges = []record.Range{{
+				Name:  "dhcp",
+				Role:  record.RoleDHCPPool,
+				First: netip.MustParseAddr("10.0.0.20"),
+				Last:  netip.MustParseAddr("10.0.0.40"),
+			}}
+			d := newTestDb(t, n)
+			putTestRecord(t, d, record.DefaultNetwork, "10.0.0.100")
+
+			err := d.SetNetwork(tt.prefix, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SetNetwork(%s) = %v; want error containing %q", tt.prefix, err, tt.wantErr)
+				}
+				if got, _ := d.GetNetworkByName(record.DefaultNetwork); got.Prefix.String() != "10.0.0.0/24" {
+					t.Errorf("prefix changed to %s despite the error", got.Prefix)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetNetwork(%s): %s", tt.prefix, err)
+			}
+		})
+	}
+}
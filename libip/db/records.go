@@ -0,0 +1,288 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ListRecords returns every record of a network ordered by address
+func (db *Db) ListRecords(network string) ([]record.Record, error) {
+	var records []record.Record
+	err := db.view(func(tx *bolt.Tx) error {
+		b := networkRecords(tx, network)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			r, err := decodeRecord(k, v)
+			if err != nil {
+				return err
+			}
+			records = append(records, r)
+			return nil
+		})
+	})
+	sort.Slice(records, func(i, j int) bool { return records[i].Addr.Less(records[j].Addr) })
+	return records, err
+}
+
+// GetRecord returns a single record by address
+func (db *Db) GetRecord(network, id string) (record.Record, error) {
+	var r record.Record
+	err := db.view(func(tx *bolt.Tx) error {
+		var err error
+		r, err = getRecord(tx, network, id)
+		return err
+	})
+	return r, err
+}
+
+// PutRecord creates or updates a record. A change of state must be a valid
+// lifecycle transition from the stored record. It returns the audit entries
+// written.
+func (db *Db) PutRecord(r record.Record) ([]record.AuditEntry, error) {
+	return db.updateAudited(func(tx *bolt.Tx) error {
+		return db.saveRecord(tx, r, time.Now())
+	})
+}
+
+// ImportRecords creates or updates a batch of records in a single
+// transaction; if any record is rejected none are stored
+func (db *Db) ImportRecords(records []record.Record) error {
+	return db.update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		for _, r := range records {
+			if err := db.saveRecord(tx, r, now); err != nil {
+				return fmt.Errorf("%s: %s", r.Addr, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRecord removes a record. Only free or reserved records may be
+// deleted; anything else must be released through quarantine first. It
+// returns the audit entries written.
+func (db *Db) DeleteRecord(network, id string) ([]record.AuditEntry, error) {
+	return db.updateAudited(func(tx *bolt.Tx) error {
+		r, err := getRecord(tx, network, id)
+		if err != nil {
+			return err
+		}
+		if r.State != record.Free && r.State != record.Reserved {
+			return fmt.Errorf("%s is %s; release it before deleting", id, r.State)
+		}
+		if err := deleteRecord(tx, r); err != nil {
+			return err
+		}
+		return db.audit(tx, record.ActionDelete, network, id, &r, nil, time.Now())
+	})
+}
+
+// SetRecordState moves a record to a new lifecycle state
+func (db *Db) SetRecordState(network, id string, to record.State) (record.Record, error) {
+	action := record.ActionState
+	if to == record.Quarantined {
+		action = record.ActionRelease
+	}
+	r, _, err := db.setRecordState(network, id, to, action)
+	return r, err
+}
+
+func (db *Db) setRecordState(network, id string, to record.State, action string) (record.Record, []record.AuditEntry, error) {
+	var r record.Record
+	entries, err := db.updateAudited(func(tx *bolt.Tx) error {
+		n, err := getNetwork(tx, network)
+		if err != nil {
+			return err
+		}
+		r, err = getRecord(tx, network, id)
+		if err != nil {
+			return err
+		}
+		old := r
+		now := time.Now()
+		if err := r.SetState(to, n, now); err != nil {
+			return err
+		}
+		if err := putRecord(tx, r); err != nil {
+			return err
+		}
+		return db.audit(tx, action, network, id, &old, &r, now)
+	})
+	return r, entries, err
+}
+
+// Release sends an allocated or deprecated address into quarantine, returning
+// the record and the audit entries written
+func (db *Db) Release(network, id string) (record.Record, []record.AuditEntry, error) {
+	return db.setRecordState(network, id, record.Quarantined, record.ActionRelease)
+}
+
+// Allocate assigns the lowest available address of a network. Addresses still
+// in quarantine are skipped; those whose quarantine has elapsed are reused.
+// With RoleNone only unranged and static addresses are considered, otherwise
+// only addresses within ranges of the requested role. It returns the record
+// and the audit entries written.
+func (db *Db) Allocate(network, hostname string, role record.Role) (record.Record, []record.AuditEntry, error) {
+	var r record.Record
+	entries, err := db.updateAudited(func(tx *bolt.Tx) error {
+		n, err := getNetwork(tx, network)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := n.CheckHostname(hostname); err != nil {
+			return err
+		}
+
+		var a allocation
+		var aerr error
+		found := false
+		err = freeAddrs(tx, n, role, now, func(addr netip.Addr, held *record.Record) bool {
+			a, aerr = newAllocation(n, addr, held, hostname, now)
+			found = true
+			return false
+		})
+		switch {
+		case err != nil:
+			return err
+		case aerr != nil:
+			return aerr
+		case !found:
+			return exhausted(n, role)
+		}
+		r = a.record
+		if err := putRecord(tx, r); err != nil {
+			return err
+		}
+		return db.audit(tx, record.ActionAllocate, network, r.ID(), a.old, &r, now)
+	})
+	return r, entries, err
+}
+
+// networkRecords returns the bucket holding a network's records, or nil if
+// nothing has been stored for it yet
+func networkRecords(tx *bolt.Tx, network string) *bolt.Bucket {
+	return tx.Bucket([]byte(ipRecordsBucket)).Bucket([]byte(network))
+}
+
+func createNetworkRecords(tx *bolt.Tx, network string) (*bolt.Bucket, error) {
+	b, err := tx.Bucket([]byte(ipRecordsBucket)).CreateBucketIfNotExists([]byte(network))
+	if err != nil {
+		return nil, fmt.Errorf("create bucket: %s", err)
+	}
+	return b, nil
+}
+
+func getRecord(tx *bolt.Tx, network, id string) (record.Record, error) {
+	var r record.Record
+	if _, err := netip.ParseAddr(id); err != nil {
+		return r, err
+	}
+	b := networkRecords(tx, network)
+	if b == nil {
+		return r, fmt.Errorf("record %s not found in %s", id, network)
+	}
+	v := b.Get([]byte(id))
+	if v == nil {
+		return r, fmt.Errorf("record %s not found in %s", id, network)
+	}
+	return decodeRecord([]byte(id), v)
+}
+
+// saveRecord validates a record against its network and any stored version
+// before writing and auditing it
+func (db *Db) saveRecord(tx *bolt.Tx, r record.Record, now time.Time) error {
+	n, err := getNetwork(tx, r.Network)
+	if err != nil {
+		return err
+	}
+	if !n.Contains(r.Addr) {
+		return fmt.Errorf("%s is outside network %s (%s)", r.Addr, n.Name, n.Prefix)
+	}
+	r.Tags = record.NormaliseTags(r.Tags)
+	if err := n.ValidateRecord(r); err != nil {
+		return err
+	}
+
+	old, err := getRecord(tx, r.Network, r.ID())
+	// Hostnames set before the naming policy are left for the lint report
+	// rather than blocking unrelated edits
+	if err != nil || !strings.EqualFold(old.Hostname, r.Hostname) {
+		if err := n.CheckHostname(r.Hostname); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		r.Created, r.Updated, r.StateChanged = now, now, now
+		if err := putRecord(tx, r); err != nil {
+			return err
+		}
+		return db.audit(tx, record.ActionCreate, r.Network, r.ID(), nil, &r, now)
+	}
+
+	want := r.State
+	r.Created = old.Created
+	r.State, r.StateChanged = old.State, old.StateChanged
+	if want != old.State {
+		if err := r.SetState(want, n, now); err != nil {
+			return err
+		}
+	}
+	r.Updated = now
+	if err := putRecord(tx, r); err != nil {
+		return err
+	}
+	return db.audit(tx, record.ActionUpdate, r.Network, r.ID(), &old, &r, now)
+}
+
+// putRecord writes a record and keeps the secondary indexes in step,
+// rejecting a hostname or MAC address already held within the network
+func putRecord(tx *bolt.Tx, r record.Record) error {
+	b, err := createNetworkRecords(tx, r.Network)
+	if err != nil {
+		return err
+	}
+	if v := b.Get([]byte(r.ID())); v != nil {
+		old, err := decodeRecord([]byte(r.ID()), v)
+		if err != nil {
+			return err
+		}
+		if err := unindexRecord(tx, old); err != nil {
+			return err
+		}
+	}
+	if err := indexRecord(tx, r); err != nil {
+		return err
+	}
+	v, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(r.ID()), v)
+}
+
+// deleteRecord removes a record and its index entries
+func deleteRecord(tx *bolt.Tx, r record.Record) error {
+	if err := unindexRecord(tx, r); err != nil {
+		return err
+	}
+	return networkRecords(tx, r.Network).Delete([]byte(r.ID()))
+}
+
+func decodeRecord(k, v []byte) (record.Record, error) {
+	var r record.Record
+	if err := json.Unmarshal(v, &r); err != nil {
+		return r, fmt.Errorf("decode record %s: %s", k, err)
+	}
+	return r, nil
+}
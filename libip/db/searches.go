@@ -0,0 +1,65 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ListSearches returns every saved search ordered by name
+func (db *Db) ListSearches() ([]record.SavedSearch, error) {
+	var searches []record.SavedSearch
+	err := db.view(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(searchesBucket)).ForEach(func(k, v []byte) error {
+			var s record.SavedSearch
+			if err := json.Unmarshal(v, &s); err != nil {
+				return fmt.Errorf("decode search %s: %s", k, err)
+			}
+			searches = append(searches, s)
+			return nil
+		})
+	})
+	sort.Slice(searches, func(i, j int) bool { return searches[i].Name < searches[j].Name })
+	return searches, err
+}
+
+// GetSearch returns the named saved search
+func (db *Db) GetSearch(name string) (record.SavedSearch, error) {
+	var s record.SavedSearch
+	err := db.view(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(searchesBucket)).Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("saved search %q not found", name)
+		}
+		return json.Unmarshal(v, &s)
+	})
+	return s, err
+}
+
+// SaveSearch creates or replaces a saved search
+func (db *Db) SaveSearch(s record.SavedSearch) error {
+	if s.Name == "" {
+		return fmt.Errorf("search name required")
+	}
+	return db.update(func(tx *bolt.Tx) error {
+		v, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(searchesBucket)).Put([]byte(s.Name), v)
+	})
+}
+
+// DeleteSearch removes a saved search
+func (db *Db) DeleteSearch(name string) error {
+	return db.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(searchesBucket))
+		if b.Get([]byte(name)) == nil {
+			return fmt.Errorf("saved search %q not found", name)
+		}
+		return b.Delete([]byte(name))
+	})
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// GetSession returns the TUI session saved by the current user, or an empty
+// session if there is none
+func (db *Db) GetSession() (record.Session, error) {
+	var s record.Session
+	err := db.view(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(sessionsBucket)).Get([]byte(db.user))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &s); err != nil {
+			return fmt.Errorf("decode session of %s: %s", db.user, err)
+		}
+		return nil
+	})
+	return s, err
+}
+
+// SaveSession stores the current user's TUI session
+func (db *Db) SaveSession(s record.Session) error {
+	return db.update(func(tx *bolt.Tx) error {
+		v, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(sessionsBucket)).Put([]byte(db.user), v)
+	})
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ListTemplates returns every stored subnet template ordered by name
+func (db *Db) ListTemplates() ([]record.Template, error) {
+	var templates []record.Template
+	err := db.view(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(templatesBucket)).ForEach(func(k, v []byte) error {
+			var t record.Template
+			if err := json.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("decode template %s: %s", k, err)
+			}
+			templates = append(templates, t)
+			return nil
+		})
+	})
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, err
+}
+
+// GetTemplate returns the named subnet template
+func (db *Db) GetTemplate(name string) (record.Template, error) {
+	var t record.Template
+	err := db.view(func(tx *bolt.Tx) error {
+		var err error
+		t, err = getTemplate(tx, name)
+		return err
+	})
+	return t, err
+}
+
+// SaveTemplate creates or replaces a subnet template
+func (db *Db) SaveTemplate(t record.Template) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	return db.update(func(tx *bolt.Tx) error {
+		return putTemplate(tx, t)
+	})
+}
+
+// DeleteTemplate removes a subnet template
+func (db *Db) DeleteTemplate(name string) error {
+	return db.update(func(tx *bolt.Tx) error {
+		if _, err := getTemplate(tx, name); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(templatesBucket)).Delete([]byte(name))
+	})
+}
+
+// CreateNetwork stores a new network, applying the named template if one is
+// given. The network and the template's placeholder records are written in a
+// single transaction.
+func (db *Db) CreateNetwork(n record.Network, template string) error {
+	if n.Name == "" {
+		return fmt.Errorf("network name required")
+	}
+	if !n.Prefix.IsValid() {
+		return fmt.Errorf("network %s: invalid prefix", n.Name)
+	}
+	n.Prefix = n.Prefix.Masked()
+	return db.update(func(tx *bolt.Tx) error {
+		if _, err := getNetwork(tx, n.Name); err == nil {
+			return fmt.Errorf("network %q already exists", n.Name)
+		}
+		return db.createNetwork(tx, n, template)
+	})
+}
+
+func (db *Db) createNetwork(tx *bolt.Tx, n record.Network, template string) error {
+	var records []record.Record
+	if template != "" {
+		t, err := getTemplate(tx, template)
+		if err != nil {
+			return err
+		}
+		if n, records, err = t.Apply(n); err != nil {
+			return err
+		}
+	}
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if err := putNetwork(tx, n); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := db.audit(tx, record.ActionNetworkCreate, n.Name, "", nil, &n, now); err != nil {
+		return err
+	}
+	for _, r := range records {
+		r.Created, r.Updated, r.StateChanged = now, now, now
+		if err := putRecord(tx, r); err != nil {
+			return err
+		}
+		if err := db.audit(tx, record.ActionCreate, n.Name, r.ID(), nil, &r, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedTemplates stores the built-in templates that are not already present
+func seedTemplates(tx *bolt.Tx) error {
+	for _, t := range record.BuiltinTemplates {
+		if _, err := getTemplate(tx, t.Name); err == nil {
+			continue
+		}
+		if err := putTemplate(tx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getTemplate(tx *bolt.Tx, name string) (record.Template, error) {
+	var t record.Template
+	v := tx.Bucket([]byte(templatesBucket)).Get([]byte(name))
+	if v == nil {
+		return t, fmt.Errorf("template %q not found", name)
+	}
+	if err := json.Unmarshal(v, &t); err != nil {
+		return t, fmt.Errorf("decode template %s: %s", name, err)
+	}
+	return t, nil
+}
+
+func putTemplate(tx *bolt.Tx, t record.Template) error {
+	v, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(templatesBucket)).Put([]byte(t.Name), v)
+}
@@ -0,0 +1,44 @@
+package libip
+
+import (
+	"fmt"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+)
+
+// Fsck checks the secondary indexes against the records, rebuilding them when
+// repair is set. Duplicate hostnames or MAC addresses cannot be repaired
+// automatically and are reported either way.
+func Fsck(c *config.Config, repair bool) error {
+	d := db.New(c)
+	defer d.Close()
+
+	problems, err := d.CheckIndexes()
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if !repair {
+		if len(problems) > 0 {
+			return fmt.Errorf("%d problems found; run with --repair to rebuild the indexes", len(problems))
+		}
+		fmt.Println("ok")
+		return nil
+	}
+
+	remaining, err := d.RebuildIndexes()
+	if err != nil {
+		return err
+	}
+	fmt.Println("indexes rebuilt")
+	for _, p := range remaining {
+		fmt.Println(p)
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("%d duplicates need fixing by hand", len(remaining))
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package libip
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/query"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+)
+
+// IpList prints the records of a network, or of every network, optionally as
+// they stood at a past moment and narrowed by a state, a --where expression
+// and a saved search. The json and csv formats export every field, tags and
+// custom fields included; csv output can be read back by ip import.
+func IpList(c *config.Config, network, state, at, where, saved string, all bool, format string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	where, err := resolveWhere(d, where, saved)
+	if err != nil {
+		return err
+	}
+	q, err := parseWhere(where, query.Records)
+	if err != nil {
+		return err
+	}
+	var filter *record.State
+	if state != "" {
+		st, err := record.ParseState(state)
+		if err != nil {
+			return err
+		}
+		filter = &st
+	}
+
+	names := []string{network}
+	if all {
+		networks, err := d.ListNetworks()
+		if err != nil {
+			return err
+		}
+		names = names[:0]
+		for _, n := range networks {
+			names = append(names, n.Name)
+		}
+	}
+
+	var networks []record.Network
+	var matched []record.Record
+	now := time.Now()
+	for _, name := range names {
+		n, records, err := networkRecordsAt(d, name, at)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, n)
+		for _, r := range records {
+			if filter != nil && r.State != *filter {
+				continue
+			}
+			if !q.Match(query.Subject{Network: n, Record: r}, now) {
+				continue
+			}
+			matched = append(matched, r)
+		}
+	}
+
+	switch format {
+	case "json":
+		if matched == nil {
+			matched = []record.Record{}
+		}
+		out, err := json.MarshalIndent(matched, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	case "csv":
+		return record.WriteCSV(os.Stdout, networks, matched)
+	}
+
+	roles := make(map[string]record.Network)
+	for _, n := range networks {
+		roles[n.Name] = n
+	}
+	headers := []string{"address", "state", "role", "hostname", "owner", "description"}
+	if all {
+		headers = append([]string{"network"}, headers...)
+	}
+	t := styles.StyledTable().Headers(headers...)
+	for _, r := range matched {
+		row := []string{r.ID(), r.State.String(), roles[r.Network].RoleOf(r.Addr).String(), r.Hostname, r.Owner, r.Description}
+		if all {
+			row = append([]string{r.Network}, row...)
+		}
+		t.Row(row...)
+	}
+	fmt.Println(t.Render())
+	return nil
+}
+
+func IpAdd(c *config.Config, network, addr, state string, r record.Record) error {
+	a, err := netip.ParseAddr(addr)
+	if err != nil {
+		return err
+	}
+	st, err := record.ParseState(state)
+	if err != nil {
+		return err
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	if _, err := d.GetRecord(network, a.String()); err == nil {
+		return fmt.Errorf("record %s already exists in %s", a, network)
+	}
+	r.Addr, r.Network, r.State = a, network, st
+	warnProviderReserved(n, r)
+	if _, err := d.PutRecord(r); err != nil {
+		return err
+	}
+	monitor(c, d)
+	return nil
+}
+
+// IpAlloc allocates one or more addresses in a single transaction and
+// prints them, or with dryRun only shows which addresses would be assigned
+func IpAlloc(c *config.Config, network, role string, req db.AllocateRequest, dryRun bool) error {
+	if role != "" {
+		var err error
+		if req.Role, err = record.ParseRole(role); err != nil {
+			return err
+		}
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	if dryRun {
+		records, err := d.PlanAllocation(network, req)
+		if err != nil {
+			return err
+		}
+		n, err := d.GetNetworkByName(network)
+		if err != nil {
+			return err
+		}
+		t := styles.StyledTable().Headers("address", "role", "hostname")
+		for _, r := range records {
+			t.Row(r.ID(), n.RoleOf(r.Addr).String(), r.Hostname)
+		}
+		fmt.Println(t.Render())
+		fmt.Printf("dry run: %d addresses would be allocated in %s\n", len(records), network)
+		return nil
+	}
+
+	records, _, err := d.AllocateMany(network, req)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		fmt.Println(r.ID())
+	}
+	monitor(c, d)
+	return nil
+}
+
+func IpRelease(c *config.Config, network, addr string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	r, _, err := d.Release(network, addr)
+	if err != nil {
+		return err
+	}
+	monitor(c, d)
+	fmt.Printf("%s quarantined until %s\n", r.ID(), r.QuarantineEnds(n).Format("2006-01-02 15:04"))
+	return nil
+}
+
+func IpSetState(c *config.Config, network, addr, state string) error {
+	st, err := record.ParseState(state)
+	if err != nil {
+		return err
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	if _, err := d.SetRecordState(network, addr, st); err != nil {
+		return err
+	}
+	monitor(c, d)
+	return nil
+}
+
+// IpShow prints every field of a record, optionally as it stood at a past
+// moment
+func IpShow(c *config.Config, network, addr, at string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	n, records, err := networkRecordsAt(d, network, at)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if r.ID() != addr {
+			continue
+		}
+		printRecord(n, r)
+		return nil
+	}
+	if at != "" {
+		return fmt.Errorf("record %s not found in %s at %s", addr, network, at)
+	}
+	return fmt.Errorf("record %s not found in %s", addr, network)
+}
+
+// IpFind prints the record of a network holding a hostname or MAC address,
+// or the records carrying a tag, looked up through the secondary indexes
+func IpFind(c *config.Config, network, hostname, mac, tag string) error {
+	given := 0
+	for _, v := range []string{hostname, mac, tag} {
+		if v != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return fmt.Errorf("give exactly one of --hostname, --mac or --tag")
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	if tag != "" {
+		records, err := d.LookupTag(network, tag)
+		if err != nil {
+			return err
+		}
+		t := styles.StyledTable().Headers("address", "state", "role", "hostname", "tags")
+		for _, r := range records {
+			t.Row(r.ID(), r.State.String(), n.RoleOf(r.Addr).String(), r.Hostname, strings.Join(r.Tags, ", "))
+		}
+		fmt.Println(t.Render())
+		return nil
+	}
+	var r record.Record
+	if hostname != "" {
+		r, err = d.LookupHostname(network, hostname)
+	} else {
+		r, err = d.LookupMAC(network, mac)
+	}
+	if err != nil {
+		return err
+	}
+	printRecord(n, r)
+	return nil
+}
+
+// printRecord prints every field of a record
+func printRecord(n record.Network, r record.Record) {
+	t := styles.StyledTable()
+	t.Rows(
+		[]string{"address", r.ID()},
+		[]string{"network", r.Network},
+		[]string{"state", r.State.String()},
+		[]string{"role", n.RoleOf(r.Addr).String()},
+		[]string{"hostname", r.Hostname},
+		[]string{"mac", r.MAC},
+		[]string{"owner", r.Owner},
+		[]string{"description", r.Description},
+		[]string{"created", r.Created.Local().Format(time.DateTime)},
+		[]string{"updated", r.Updated.Local().Format(time.DateTime)},
+	)
+	if len(r.Tags) > 0 {
+		t.Row("tags", strings.Join(r.Tags, ", "))
+	}
+	for _, f := range n.Fields {
+		t.Row(record.CustomColumn+f.Name, r.Fields[f.Name])
+	}
+	fmt.Println(t.Render())
+}
+
+// networkRecordsAt loads a network and its records, as they stood at the
+// given moment when at is set
+func networkRecordsAt(d *db.Db, network, at string) (record.Network, []record.Record, error) {
+	if at == "" {
+		n, err := d.GetNetworkByName(network)
+		if err != nil {
+			return n, nil, err
+		}
+		records, err := d.ListRecords(network)
+		return n, records, err
+	}
+
+	t, err := record.ParseTime(at, time.Now())
+	if err != nil {
+		return record.Network{}, nil, fmt.Errorf("--at: %s", err)
+	}
+	n, err := d.NetworkAt(network, t)
+	if err != nil {
+		return n, nil, err
+	}
+	records, err := d.RecordsAt(network, t)
+	return n, records, err
+}
+
+// IpTag adds tags to a record, or removes them when remove is set
+func IpTag(c *config.Config, network, addr string, tags []string, remove bool) error {
+	d := db.New(c)
+	defer d.Close()
+
+	r, err := d.GetRecord(network, addr)
+	if err != nil {
+		return err
+	}
+	tags = record.NormaliseTags(tags)
+	if remove {
+		r.Tags = slices.DeleteFunc(r.Tags, func(t string) bool { return slices.Contains(tags, t) })
+	} else {
+		r.Tags = append(r.Tags, tags...)
+	}
+	if _, err := d.PutRecord(r); err != nil {
+		return err
+	}
+	monitor(c, d)
+	return nil
+}
+
+// IpSetFields sets custom fields of a record from name=value pairs; an empty
+// value clears the field
+func IpSetFields(c *config.Config, network, addr string, fields map[string]string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	r, err := d.GetRecord(network, addr)
+	if err != nil {
+		return err
+	}
+	for name, v := range fields {
+		r.SetField(strings.ToLower(name), v)
+	}
+	if _, err := d.PutRecord(r); err != nil {
+		return err
+	}
+	monitor(c, d)
+	return nil
+}
+
+func IpRemove(c *config.Config, network, addr string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	if _, err := d.DeleteRecord(network, addr); err != nil {
+		return err
+	}
+	monitor(c, d)
+	return nil
+}
+
+// IpImport reads records from a CSV file with a header row naming the
+// columns: address (required), state, hostname, mac, owner, description,
+// tags and a field.<name> column per custom field. Records are stored
+// all-or-nothing.
+func IpImport(c *config.Config, network, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return fmt.Errorf("read %s: %s", file, err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%s is empty", file)
+	}
+	cols := make(map[string]int)
+	for i, h := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["address"]; !ok {
+		return fmt.Errorf("%s: missing address column", file)
+	}
+	field := func(row []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	var records []record.Record
+	for i, row := range rows[1:] {
+		addr, err := netip.ParseAddr(field(row, "address"))
+		if err != nil {
+			return fmt.Errorf("%s line %d: %s", file, i+2, err)
+		}
+		st := record.Reserved
+		if s := field(row, "state"); s != "" {
+			if st, err = record.ParseState(s); err != nil {
+				return fmt.Errorf("%s line %d: %s", file, i+2, err)
+			}
+		}
+		r := record.Record{
+			Addr:        addr,
+			Network:     network,
+			State:       st,
+			Hostname:    field(row, "hostname"),
+			MAC:         field(row, "mac"),
+			Owner:       field(row, "owner"),
+			Description: field(row, "description"),
+			Tags:        record.ParseTags(field(row, "tags")),
+		}
+		for col := range cols {
+			if name, ok := strings.CutPrefix(col, record.CustomColumn); ok {
+				r.SetField(name, field(row, col))
+			}
+		}
+		warnProviderReserved(n, r)
+		records = append(records, r)
+	}
+	if err := d.ImportRecords(records); err != nil {
+		return err
+	}
+	fmt.Printf("imported %d records into %s\n", len(records), network)
+	monitor(c, d)
+	return nil
+}
+
+// warnProviderReserved prints a warning when a record sits on an address the
+// network's cloud provider holds back
+func warnProviderReserved(n record.Network, r record.Record) {
+	if n.ProviderReserved(r.Addr) {
+		fmt.Fprintln(os.Stderr, styles.WarnStyle.Render(
+			fmt.Sprintf("warning: %s collides with an address reserved by %s", r.Addr, n.Provider)))
+	}
+}
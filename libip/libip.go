@@ -8,12 +8,15 @@ import (
 	"github.com/bakedSpaceTime/binip/libip/app"
 	"github.com/bakedSpaceTime/binip/libip/config"
 	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/styles"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/davecgh/go-spew/spew"
 )
 
-func Info(c *config.Config) error {
+// Info prints system details and a preview of the active theme, and with
+// palette the terminal's 256 colours
+func Info(c *config.Config, palette bool) error {
 	fmt.Println("System info")
 	fmt.Println("\tNum Logical CPU:", runtime.NumCPU())
 	fmt.Println("\tOperating System:", runtime.GOOS)
@@ -21,7 +24,11 @@ func Info(c *config.Config) error {
 	fmt.Println("\tDebug:", c.Debug)
 	fmt.Println("\tDebug File:", c.DebugFile)
 
-	for i := 0; i < 256; i++ {
+	fmt.Println()
+	fmt.Println(styles.Preview())
+	fmt.Println()
+
+	for i := 0; palette && i < 256; i++ {
 		style := lipgloss.NewStyle().
 			Background(lipgloss.Color(fmt.Sprintf("%d", i))).
 			Width(4)
@@ -30,7 +37,9 @@ func Info(c *config.Config) error {
 			fmt.Println()
 		}
 	}
-	fmt.Println()
+	if palette {
+		fmt.Println()
+	}
 	fmt.Println(db.New(c).String())
 	return nil
 }
@@ -49,8 +58,17 @@ func App(c *config.Config) error {
 		defer dump.Close()
 	}
 	c.DebugWriter = dump
+	c.Source = "tui"
 
-	p := tea.NewProgram(app.New(c), tea.WithAltScreen())
+	m, err := app.New(c)
+	if err != nil {
+		return err
+	}
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if c.Mouse {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(m, opts...)
 	if _, err = p.Run(); err != nil {
 		fmt.Println("could not start program:", err)
 	}
@@ -0,0 +1,317 @@
+package libip
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/query"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+)
+
+func NetworkList(c *config.Config, where string) error {
+	q, err := parseWhere(where, query.Networks)
+	if err != nil {
+		return err
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	networks, err := d.ListNetworks()
+	if err != nil {
+		return err
+	}
+	t := styles.StyledTable().Headers("name", "prefix", "quarantine")
+	now := time.Now()
+	for _, n := range networks {
+		if !q.Match(query.Subject{Network: n}, now) {
+			continue
+		}
+		t.Row(n.Name, n.Prefix.String(), n.Quarantine.String())
+	}
+	fmt.Println(t.Render())
+	return nil
+}
+
+// NetworkAdd creates a network. A nil quarantine takes the configured
+// default.
+func NetworkAdd(c *config.Config, name, prefix, template, provider string, quarantine *time.Duration) error {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	pr, err := record.ParseProvider(provider)
+	if err != nil {
+		return err
+	}
+	q := c.Quarantine
+	if quarantine != nil {
+		q = *quarantine
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	if err := d.CreateNetwork(record.Network{Name: name, Prefix: p, Quarantine: q, Provider: pr}, template); err != nil {
+		return err
+	}
+	monitor(c, d)
+	return nil
+}
+
+func NetworkSetProvider(c *config.Config, name, provider string) error {
+	pr, err := record.ParseProvider(provider)
+	if err != nil {
+		return err
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(name)
+	if err != nil {
+		return err
+	}
+	n.Provider = pr
+	if err := d.SaveNetwork(n); err != nil {
+		return err
+	}
+
+	// Existing records are left alone but flagged so they can be moved
+	records, err := d.ListRecords(name)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		warnProviderReserved(n, r)
+	}
+	return nil
+}
+
+func NetworkSetQuarantine(c *config.Config, name string, quarantine time.Duration) error {
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(name)
+	if err != nil {
+		return err
+	}
+	n.Quarantine = quarantine
+	return d.SaveNetwork(n)
+}
+
+func NetworkShow(c *config.Config, name, at string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	n, _, err := networkRecordsAt(d, name, at)
+	if err != nil {
+		return err
+	}
+	t := styles.StyledTable()
+	t.Rows(
+		[]string{"name", n.Name},
+		[]string{"prefix", n.Prefix.String()},
+		[]string{"quarantine", n.Quarantine.String()},
+		[]string{"provider", n.Provider.String()},
+		[]string{"usable hosts", strconv.FormatUint(n.UsableHosts(), 10)},
+	)
+	if n.Naming.Template != "" {
+		t.Row("naming template", n.Naming.Template)
+	}
+	if re, err := n.Naming.Regexp(); err == nil && re != nil {
+		t.Row("naming pattern", re.String())
+	}
+	for _, k := range slices.Sorted(maps.Keys(n.Naming.Vars)) {
+		t.Row("naming "+k, n.Naming.Vars[k])
+	}
+	keys := make([]string, 0, len(n.Metadata))
+	for k := range n.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		t.Row(k, n.Metadata[k])
+	}
+	fmt.Println(t.Render())
+
+	if len(n.Fields) > 0 {
+		f := styles.StyledTable().Headers("field", "type", "values")
+		for _, fd := range n.Fields {
+			f.Row(fd.Name, fd.Type.String(), strings.Join(fd.Values, ", "))
+		}
+		fmt.Println(f.Render())
+	}
+	if len(n.Ranges) > 0 {
+		r := styles.StyledTable().Headers("range", "role", "addresses")
+		for _, rg := range n.Ranges {
+			r.Row(rg.Name, styles.RoleStyle(rg.Role.String()).Render(rg.Role.String()), rg.String())
+		}
+		fmt.Println(r.Render())
+	}
+	return nil
+}
+
+func NetworkRangeAdd(c *config.Config, network, name, role, span string) error {
+	ro, err := record.ParseRole(role)
+	if err != nil {
+		return err
+	}
+	rg, err := record.ParseRange(name, ro, span)
+	if err != nil {
+		return err
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	n.Ranges = append(n.Ranges, rg)
+	if err := d.SaveNetwork(n); err != nil {
+		return err
+	}
+	monitor(c, d)
+	return nil
+}
+
+func NetworkRangeRemove(c *config.Config, network, name string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	for i, rg := range n.Ranges {
+		if rg.Name == name {
+			n.Ranges = append(n.Ranges[:i], n.Ranges[i+1:]...)
+			if err := d.SaveNetwork(n); err != nil {
+				return err
+			}
+			monitor(c, d)
+			return nil
+		}
+	}
+	return fmt.Errorf("network %s has no range %q", network, name)
+}
+
+// NetworkSetNaming replaces a network's hostname naming policy, or removes it
+// with clear. Existing records are not changed; see NetworkLint.
+func NetworkSetNaming(c *config.Config, name string, naming record.Naming, clear bool) error {
+	if !clear && naming.Template == "" && naming.Pattern == "" {
+		return fmt.Errorf("give a --template or --pattern, or --clear")
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(name)
+	if err != nil {
+		return err
+	}
+	n.Naming = naming
+	if clear {
+		n.Naming = record.Naming{}
+	}
+	if err := d.SaveNetwork(n); err != nil {
+		return err
+	}
+	if !clear {
+		return lintNetwork(d, n)
+	}
+	return nil
+}
+
+// NetworkLint reports the records of a network, or of every network, whose
+// hostnames break the naming policy
+func NetworkLint(c *config.Config, name string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	var networks []record.Network
+	if name != "" {
+		n, err := d.GetNetworkByName(name)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, n)
+	} else {
+		var err error
+		if networks, err = d.ListNetworks(); err != nil {
+			return err
+		}
+	}
+
+	problems := 0
+	t := styles.StyledTable().Headers("network", "address", "hostname", "problem")
+	for _, n := range networks {
+		records, err := d.ListRecords(n.Name)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := n.CheckHostname(r.Hostname); err != nil {
+				t.Row(n.Name, r.ID(), r.Hostname, fmt.Sprintf("does not match %s", n.Naming))
+				problems++
+			}
+		}
+	}
+	if problems == 0 {
+		fmt.Println("ok")
+		return nil
+	}
+	fmt.Println(t.Render())
+	return fmt.Errorf("%d hostnames break the naming policy", problems)
+}
+
+// lintNetwork warns about records whose hostnames break a newly set policy
+func lintNetwork(d *db.Db, n record.Network) error {
+	records, err := d.ListRecords(n.Name)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, r := range records {
+		if n.CheckHostname(r.Hostname) != nil {
+			count++
+		}
+	}
+	if count > 0 {
+		fmt.Fprintln(os.Stderr, styles.WarnStyle.Render(
+			fmt.Sprintf("warning: %d existing hostnames do not match; run network lint %s to list them", count, n.Name)))
+	}
+	return nil
+}
+
+// NetworkFieldAdd defines a custom field records of the network may carry
+func NetworkFieldAdd(c *config.Config, network, name, typ string, values []string) error {
+	ft, err := record.ParseFieldType(typ)
+	if err != nil {
+		return err
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	n, err := d.GetNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	n.Fields = append(n.Fields, record.FieldDef{Name: strings.ToLower(name), Type: ft, Values: values})
+	return d.SaveNetwork(n)
+}
+
+// NetworkFieldRemove drops a custom field and every value recorded for it
+func NetworkFieldRemove(c *config.Config, network, name string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	return d.RemoveNetworkField(network, strings.ToLower(name))
+}
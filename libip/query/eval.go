@@ -0,0 +1,186 @@
+package query
+
+import (
+	"cmp"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+// expr is a node of the parsed expression tree
+type expr interface {
+	eval(s Subject, now time.Time) bool
+}
+
+type andExpr struct{ left, right expr }
+
+func (e andExpr) eval(s Subject, now time.Time) bool {
+	return e.left.eval(s, now) && e.right.eval(s, now)
+}
+
+type orExpr struct{ left, right expr }
+
+func (e orExpr) eval(s Subject, now time.Time) bool {
+	return e.left.eval(s, now) || e.right.eval(s, now)
+}
+
+type notExpr struct{ x expr }
+
+func (e notExpr) eval(s Subject, now time.Time) bool {
+	return !e.x.eval(s, now)
+}
+
+// compareExpr compares a field against one value, or against a list of
+// values for in and not in
+type compareExpr struct {
+	field  field
+	op     string
+	values []any
+}
+
+func (e compareExpr) eval(s Subject, now time.Time) bool {
+	got := e.field.get(s)
+	if tags, ok := got.([]string); ok {
+		return e.evalTags(tags, now)
+	}
+	return e.evalValue(got, now)
+}
+
+// evalValue applies the comparison to a single field value
+func (e compareExpr) evalValue(got any, now time.Time) bool {
+	switch e.op {
+	case "in":
+		return e.any(got, now)
+	case "not in":
+		return !e.any(got, now)
+	case "~":
+		pattern := e.values[0]
+		if c, ok := got.(customValue); ok {
+			got, pattern = c.value, pattern.(customLiteral).text
+		}
+		return strings.Contains(strings.ToLower(got.(string)), strings.ToLower(pattern.(string)))
+	}
+
+	c, ok := compare(got, e.values[0], now)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// evalTags applies the comparison to a set of tags: == and in hold when any
+// tag matches, != and not in when none does
+func (e compareExpr) evalTags(tags []string, now time.Time) bool {
+	positive := e
+	switch e.op {
+	case "!=":
+		positive.op = "=="
+	case "not in":
+		positive.op = "in"
+	}
+	negate := positive.op != e.op
+	for _, t := range tags {
+		if positive.evalValue(t, now) {
+			return !negate
+		}
+	}
+	return negate
+}
+
+// any reports whether the field equals, or for prefixes lies within, any of
+// the values
+func (e compareExpr) any(got any, now time.Time) bool {
+	for _, v := range e.values {
+		if p, ok := v.(netip.Prefix); ok {
+			switch g := got.(type) {
+			case netip.Addr:
+				if p.Contains(g) {
+					return true
+				}
+				continue
+			case netip.Prefix:
+				if p.Bits() <= g.Bits() && p.Contains(g.Addr()) {
+					return true
+				}
+				continue
+			}
+		}
+		if c, ok := compare(got, v, now); ok && c == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compare orders a field value against a literal of the same kind. Strings
+// compare case-insensitively. Integer and date custom fields compare as
+// numbers and times, and fail to compare when the record has no value.
+func compare(got, want any, now time.Time) (int, bool) {
+	switch g := got.(type) {
+	case customValue:
+		w, ok := want.(customLiteral)
+		if !ok {
+			return 0, false
+		}
+		switch g.def.Type {
+		case record.FieldInt:
+			a, errA := strconv.ParseInt(g.value, 10, 64)
+			b, errB := strconv.ParseInt(w.text, 10, 64)
+			if errA != nil || errB != nil {
+				return 0, false
+			}
+			return cmp.Compare(a, b), true
+		case record.FieldDate:
+			d, err := time.ParseInLocation(time.DateOnly, g.value, time.Local)
+			if err != nil || w.time == nil {
+				return 0, false
+			}
+			return d.Compare(w.time.resolve(now)), true
+		}
+		return strings.Compare(strings.ToLower(g.value), strings.ToLower(w.text)), true
+	case string:
+		w, ok := want.(string)
+		return strings.Compare(strings.ToLower(g), strings.ToLower(w)), ok
+	case netip.Addr:
+		w, ok := want.(netip.Addr)
+		return g.Compare(w), ok
+	case netip.Prefix:
+		w, ok := want.(netip.Prefix)
+		if !ok {
+			return 0, false
+		}
+		if c := g.Addr().Compare(w.Addr()); c != 0 {
+			return c, true
+		}
+		return g.Bits() - w.Bits(), true
+	case time.Time:
+		w, ok := want.(timeValue)
+		return g.Compare(w.resolve(now)), ok
+	case time.Duration:
+		w, ok := want.(time.Duration)
+		return cmp.Compare(g, w), ok
+	default:
+		// States, roles and providers are enums compared for equality only
+		if got == want {
+			return 0, true
+		}
+		return 1, true
+	}
+}
@@ -0,0 +1,72 @@
+package query
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	n := record.Network{
+		Name:   "lab",
+		Prefix: netip.MustParsePrefix("10.0.0.0/24"),
+		Fields: []record.FieldDef{
+			{Name: "expires", Type: record.FieldDate},
+			{Name: "rack", Type: record.FieldString},
+			{Name: "u", Type: record.FieldInt},
+		},
+	}
+	subject := func(addr string, fields map[string]string, tags ...string) Subject {
+		return Subject{Network: n, Record: record.Record{
+			Addr:     netip.MustParseAddr(addr),
+			Network:  "lab",
+			State:    record.Allocated,
+			Hostname: "web-" + addr,
+			Tags:     tags,
+			Created:  now.Add(-48 * time.Hour),
+			Fields:   fields,
+		}}
+	}
+	soon := subject("10.0.0.1", map[string]string{"expires": "2026-10-22", "rack": "A1", "u": "9"}, "db")
+	later := subject("10.0.0.2", map[string]string{"expires": "2027-01-01", "u": "10"})
+	undated := subject("10.0.0.3", nil, "web")
+
+	tests := []struct {
+		query string
+		want  []Subject
+	}{
+		{`field.expires < now+7d`, []Subject{soon}},
+		{`field.expires >= now+7d`, []Subject{later}},
+		{`field.expires == 2027-01-01`, []Subject{later}},
+		{`field.expires != 2027-01-01`, []Subject{soon}},
+		{`field.u > 9`, []Subject{later}},
+		{`field.rack ~ a`, []Subject{soon}},
+		{`network == "lab" and tag in ["db"] and field.expires < now+7d and ip in 10.0.0.0/30`, []Subject{soon}},
+		{`tag != db`, []Subject{later, undated}},
+		{`created < now-1d and not ip == 10.0.0.2`, []Subject{soon, undated}},
+		{`ip in [10.0.0.2, 10.0.0.3] or hostname ~ "WEB-10.0.0.1"`, []Subject{soon, later, undated}},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query, Records)
+		if err != nil {
+			t.Fatalf("Parse(%q): %s", tt.query, err)
+		}
+		var got []string
+		for _, s := range []Subject{soon, later, undated} {
+			if q.Match(s, now) {
+				got = append(got, s.Record.ID())
+			}
+		}
+		var want []string
+		for _, s := range tt.want {
+			want = append(want, s.Record.ID())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s matched %v; want %v", tt.query, got, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+// token is a lexeme and the 1-based column it starts at
+type token struct {
+	kind tokenKind
+	text string
+	col  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// Error is a query syntax or type error at a column of the input
+type Error struct {
+	Col int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Col, e.Msg)
+}
+
+func errorAt(col int, format string, args ...any) *Error {
+	return &Error{Col: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// operators lists the symbolic operators, longest first
+var operators = []string{"==", "!=", "<=", ">=", "<", ">", "~"}
+
+// isWordRune reports whether r may appear in a bare word. Bare words cover
+// identifiers, keywords and unquoted literals such as 10.0.0.0/20 and now+7d.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._:/-+", r)
+}
+
+// lex splits a query into tokens
+func lex(s string) ([]token, error) {
+	var toks []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		col := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", col})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", col})
+			i++
+		case r == '[':
+			toks = append(toks, token{tokLBracket, "[", col})
+			i++
+		case r == ']':
+			toks = append(toks, token{tokRBracket, "]", col})
+			i++
+		case r == ',':
+			toks = append(toks, token{tokComma, ",", col})
+			i++
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, errorAt(col, "unterminated string")
+			}
+			toks = append(toks, token{tokString, b.String(), col})
+			i = j + 1
+		case isWordRune(r):
+			j := i
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+			toks = append(toks, token{tokWord, string(runes[i:j]), col})
+			i = j
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(string(runes[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				if r == '=' {
+					return nil, errorAt(col, "unexpected \"=\", use \"==\" to compare")
+				}
+				return nil, errorAt(col, "unexpected %q", r)
+			}
+			toks = append(toks, token{tokOp, op, col})
+			i += len([]rune(op))
+		}
+	}
+	return append(toks, token{tokEOF, "", len(runes) + 1}), nil
+}
+
+// Explain renders an error with the query and a caret under the column at
+// fault
+func Explain(src string, err error) string {
+	qe, ok := err.(*Error)
+	if !ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s\n  %s\n  %s^", qe, src, strings.Repeat(" ", max(qe.Col-1, 0)))
+}
@@ -0,0 +1,318 @@
+package query
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+// Parse parses a filter expression for the target. An empty expression
+// matches everything. Errors are *Error values carrying the column at fault.
+//
+//	expr    = or
+//	or      = and { "or" and }
+//	and     = unary { "and" unary }
+//	unary   = "not" unary | "(" expr ")" | compare
+//	compare = field op value | field ["not"] "in" ( value | list )
+//	list    = "[" value { "," value } "]"
+func Parse(src string, t Target) (*Query, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, target: t}
+	q := &Query{src: src}
+	if p.peek().kind == tokEOF {
+		return q, nil
+	}
+	if q.root, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorAt(tok.col, "expected \"and\" or \"or\", found %s", tok)
+	}
+	return q, nil
+}
+
+type parser struct {
+	toks   []token
+	pos    int
+	target Target
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given bare word
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokWord && strings.EqualFold(tok.text, word)
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	switch tok := p.peek(); {
+	case p.keyword("not"):
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{x}, nil
+	case tok.kind == tokLParen:
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorAt(closing.col, "expected \")\" to close \"(\" at column %d, found %s", tok.col, closing)
+		}
+		return x, nil
+	default:
+		return p.parseCompare()
+	}
+}
+
+func (p *parser) parseCompare() (expr, error) {
+	tok := p.next()
+	if tok.kind != tokWord {
+		return nil, errorAt(tok.col, "expected a field name, found %s", tok)
+	}
+	f, ok := p.target.lookup(tok.text)
+	if !ok {
+		return nil, errorAt(tok.col, "unknown field %q (fields: %s)", tok.text, strings.Join(p.target.FieldNames(), ", "))
+	}
+
+	opTok := p.next()
+	op := opTok.text
+	switch {
+	case opTok.kind == tokOp:
+	case opTok.kind == tokWord && strings.EqualFold(op, "in"):
+		op = "in"
+	case opTok.kind == tokWord && strings.EqualFold(op, "not") && p.keyword("in"):
+		p.next()
+		op = "not in"
+	default:
+		return nil, errorAt(opTok.col, "expected an operator after %q, found %s", tok.text, opTok)
+	}
+	if !allowed(f.kind, op) {
+		return nil, errorAt(opTok.col, "operator %q does not apply to %s", op, f.name)
+	}
+
+	c := compareExpr{field: f, op: op}
+	if op == "in" || op == "not in" {
+		if p.peek().kind == tokLBracket {
+			return p.parseList(c)
+		}
+		// A single prefix is shorthand for containment
+		v, err := p.parseValue(f, true)
+		if err != nil {
+			return nil, err
+		}
+		c.values = []any{v}
+		return c, nil
+	}
+	v, err := p.parseValue(f, false)
+	if err != nil {
+		return nil, err
+	}
+	c.values = []any{v}
+	return c, nil
+}
+
+func (p *parser) parseList(c compareExpr) (expr, error) {
+	open := p.next()
+	for {
+		v, err := p.parseValue(c.field, true)
+		if err != nil {
+			return nil, err
+		}
+		c.values = append(c.values, v)
+		switch tok := p.next(); tok.kind {
+		case tokComma:
+			continue
+		case tokRBracket:
+			return c, nil
+		default:
+			return nil, errorAt(tok.col, "expected \",\" or \"]\" to close \"[\" at column %d, found %s", open.col, tok)
+		}
+	}
+}
+
+// parseValue parses a literal of the field's kind. Within in, addresses may
+// also be given as prefixes.
+func (p *parser) parseValue(f field, in bool) (any, error) {
+	tok := p.next()
+	if tok.kind != tokWord && tok.kind != tokString {
+		return nil, errorAt(tok.col, "expected a value for %s, found %s", f.name, tok)
+	}
+	v, err := literal(f.kind, tok.text, in)
+	if err != nil {
+		return nil, errorAt(tok.col, "%s: %s", f.name, err)
+	}
+	return v, nil
+}
+
+// literal converts the text of a value to the Go type compared against the
+// field
+func literal(k kind, s string, in bool) (any, error) {
+	switch k {
+	case kindAddr:
+		if strings.Contains(s, "/") && in {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid prefix %q", s)
+			}
+			return p.Masked(), nil
+		}
+		a, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return a, nil
+	case kindPrefix:
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %q", s)
+		}
+		return p.Masked(), nil
+	case kindTime:
+		return parseTime(s)
+	case kindDuration:
+		return parseDuration(s)
+	case kindState:
+		return record.ParseState(s)
+	case kindRole:
+		return record.ParseRole(s)
+	case kindProvider:
+		return record.ParseProvider(s)
+	case kindTags:
+		return strings.ToLower(s), nil
+	case kindCustom:
+		c := customLiteral{text: s}
+		if t, err := parseTime(s); err == nil {
+			c.time = &t
+		}
+		return c, nil
+	default:
+		return s, nil
+	}
+}
+
+// allowed reports whether an operator applies to a kind of field
+func allowed(k kind, op string) bool {
+	switch op {
+	case "==", "!=", "in", "not in":
+		return true
+	case "~":
+		return k == kindString || k == kindTags || k == kindCustom
+	default: // ordering
+		return k == kindAddr || k == kindTime || k == kindDuration || k == kindCustom
+	}
+}
+
+// timeValue is an absolute time or an offset from now
+type timeValue struct {
+	relative bool
+	offset   time.Duration
+	abs      time.Time
+}
+
+func (t timeValue) resolve(now time.Time) time.Time {
+	if t.relative {
+		return now.Add(t.offset)
+	}
+	return t.abs
+}
+
+// parseTime accepts now, now+7d, now-24h, a date, a date and time or an RFC
+// 3339 timestamp
+func parseTime(s string) (timeValue, error) {
+	if rest, ok := strings.CutPrefix(strings.ToLower(s), "now"); ok {
+		if rest == "" {
+			return timeValue{relative: true}, nil
+		}
+		sign := time.Duration(1)
+		switch rest[0] {
+		case '+':
+		case '-':
+			sign = -1
+		default:
+			return timeValue{}, fmt.Errorf("invalid time %q, want now+DURATION or now-DURATION", s)
+		}
+		d, err := parseDuration(rest[1:])
+		if err != nil {
+			return timeValue{}, err
+		}
+		return timeValue{relative: true, offset: sign * d}, nil
+	}
+	for _, layout := range []string{time.DateOnly, time.DateTime, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return timeValue{abs: t}, nil
+		}
+	}
+	return timeValue{}, fmt.Errorf("invalid time %q, want now, now-7d, a date or RFC 3339", s)
+}
+
+// parseDuration extends time.ParseDuration with d (day) and w (week) units
+func parseDuration(s string) (time.Duration, error) {
+	for unit, size := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, unit); ok {
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(f * float64(size)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,38 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorColumn(t *testing.T) {
+	tests := []struct {
+		src string
+		col int
+		msg string
+	}{
+		{`hostname = "x"`, 10, `use "==" to compare`},
+		{`hostname == "x`, 13, "unterminated string"},
+		{`colour == red`, 1, `unknown field "colour"`},
+		{`state == allocated and`, 23, "expected a field name, found end of query"},
+		{`(state == free`, 15, `expected ")" to close "(" at column 1`},
+		{`state == nope`, 10, "state:"},
+		{`ip in [10.0.0.1 10.0.0.2]`, 17, `expected "," or "]"`},
+		{`hostname ~`, 11, "expected a value for hostname"},
+		{`state == free owner == x`, 15, `expected "and" or "or"`},
+		// Columns count characters, not bytes
+		{`hostname == "é" and bogus == 1`, 21, `unknown field "bogus"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src, Records)
+		var qe *Error
+		if !errors.As(err, &qe) {
+			t.Errorf("Parse(%q) = %v; want a query error", tt.src, err)
+			continue
+		}
+		if qe.Col != tt.col || !strings.Contains(qe.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = column %d: %s; want column %d: ...%s...", tt.src, qe.Col, qe.Msg, tt.col, tt.msg)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package query implements the filter expression language used to select
+// records and networks, e.g.
+//
+//	network == "lab" and state in [allocated, reserved] and ip in 10.0.0.0/20
+//
+// Expressions combine comparisons with and, or, not and parentheses. Records
+// match tag == x when they carry the tag, and custom fields are queried as
+// field.<name>, compared numerically when the network defines them as int.
+package query
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+// kind is the type of a field, which decides the operators and literals it
+// accepts
+type kind uint8
+
+const (
+	kindString kind = iota
+	kindAddr
+	kindPrefix
+	kindTime
+	kindDuration
+	kindState
+	kindRole
+	kindProvider
+	kindTags
+	kindCustom
+)
+
+// Target is what a query selects: records or networks
+type Target uint8
+
+const (
+	Records Target = iota
+	Networks
+)
+
+// field describes a queryable field and how to read it from a subject
+type field struct {
+	name string
+	kind kind
+	get  func(s Subject) any
+}
+
+// Subject is the network, and for record queries the record, a query is
+// evaluated against
+type Subject struct {
+	Network record.Network
+	Record  record.Record
+}
+
+var networkFields = []field{
+	{"network", kindString, func(s Subject) any { return s.Network.Name }},
+	{"prefix", kindPrefix, func(s Subject) any { return s.Network.Prefix }},
+	{"provider", kindProvider, func(s Subject) any { return s.Network.Provider }},
+	{"quarantine", kindDuration, func(s Subject) any { return s.Network.Quarantine }},
+	{"template", kindString, func(s Subject) any { return s.Network.Metadata["template"] }},
+}
+
+var recordFields = append([]field{
+	{"ip", kindAddr, func(s Subject) any { return s.Record.Addr }},
+	{"state", kindState, func(s Subject) any { return s.Record.State }},
+	{"role", kindRole, func(s Subject) any { return s.Network.RoleOf(s.Record.Addr) }},
+	{"hostname", kindString, func(s Subject) any { return s.Record.Hostname }},
+	{"mac", kindString, func(s Subject) any { return s.Record.MAC }},
+	{"owner", kindString, func(s Subject) any { return s.Record.Owner }},
+	{"description", kindString, func(s Subject) any { return s.Record.Description }},
+	{"created", kindTime, func(s Subject) any { return s.Record.Created }},
+	{"updated", kindTime, func(s Subject) any { return s.Record.Updated }},
+	{"state_changed", kindTime, func(s Subject) any { return s.Record.StateChanged }},
+	{"tag", kindTags, func(s Subject) any { return s.Record.Tags }},
+}, networkFields...)
+
+// customPrefix introduces a custom record field in a query
+const customPrefix = "field."
+
+// customValue is a custom field value and the definition it is typed by, if
+// the subject's network defines the field
+type customValue struct {
+	def   record.FieldDef
+	value string
+}
+
+// customLiteral is a value compared against a custom field. The field's
+// type is only known per network, so the text is kept along with its reading
+// as a time for date fields.
+type customLiteral struct {
+	text string
+	time *timeValue
+}
+
+// customField returns the queryable field for a custom record field
+func customField(name string) field {
+	return field{customPrefix + name, kindCustom, func(s Subject) any {
+		def, _ := s.Network.Field(name)
+		return customValue{def: def, value: s.Record.Fields[name]}
+	}}
+}
+
+// aliases are alternative names accepted for fields
+var aliases = map[string]string{
+	"addr":    "ip",
+	"address": "ip",
+	"host":    "hostname",
+	"name":    "network",
+	"desc":    "description",
+	"tags":    "tag",
+}
+
+func (t Target) fields() []field {
+	if t == Networks {
+		return networkFields
+	}
+	return recordFields
+}
+
+func (t Target) lookup(name string) (field, bool) {
+	name = strings.ToLower(name)
+	if custom, ok := strings.CutPrefix(name, customPrefix); ok && t == Records && custom != "" {
+		return customField(custom), true
+	}
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	for _, f := range t.fields() {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// FieldNames lists the fields a target can be queried on
+func (t Target) FieldNames() []string {
+	var names []string
+	for _, f := range t.fields() {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	if t == Records {
+		names = append(names, customPrefix+"<name>")
+	}
+	return names
+}
+
+// Query is a parsed filter expression
+type Query struct {
+	src  string
+	root expr
+}
+
+// String returns the expression the query was parsed from
+func (q *Query) String() string {
+	return q.src
+}
+
+// Match reports whether the subject satisfies the query. Relative times such
+// as now-7d are resolved against now.
+func (q *Query) Match(s Subject, now time.Time) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.eval(s, now)
+}
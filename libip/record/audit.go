@@ -0,0 +1,149 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Audit actions
+const (
+	ActionCreate        = "create"
+	ActionUpdate        = "update"
+	ActionDelete        = "delete"
+	ActionAllocate      = "allocate"
+	ActionRelease       = "release"
+	ActionState         = "state"
+	ActionMove          = "move"
+	ActionNetworkCreate = "network-create"
+	ActionNetworkUpdate = "network-update"
+	ActionUndo          = "undo"
+	ActionRedo          = "redo"
+)
+
+// AuditEntry is one mutation of the address plan. Before and After hold the
+// full JSON of the record or network, and are empty on create and delete
+// respectively.
+type AuditEntry struct {
+	Seq     uint64          `json:"seq"`
+	Time    time.Time       `json:"time"`
+	User    string          `json:"user"`
+	Source  string          `json:"source"`
+	Action  string          `json:"action"`
+	Network string          `json:"network"`
+	Record  string          `json:"record,omitempty"`
+	Before  json.RawMessage `json:"before,omitempty"`
+	After   json.RawMessage `json:"after,omitempty"`
+}
+
+// Change is a single field that differs between the before and after states
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Changes returns the top-level fields that differ between Before and After,
+// ordered by field name
+func (e AuditEntry) Changes() ([]Change, error) {
+	before, err := fields(e.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := fields(e.After)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for k := range before {
+		names[k] = true
+	}
+	for k := range after {
+		names[k] = true
+	}
+	var changes []Change
+	for k := range names {
+		// Bookkeeping timestamps change on every write and only add noise
+		if k == "updated" || k == "created" || k == "state_changed" {
+			continue
+		}
+		if b, a := before[k], after[k]; b != a {
+			changes = append(changes, Change{Field: k, Before: b, After: a})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// summaryWidth caps each value shown by Summary
+const summaryWidth = 32
+
+// Summary renders the changes on one line, e.g. "state: free → allocated".
+// Identity fields are left out as they are shown alongside the entry.
+func (e AuditEntry) Summary() string {
+	changes, err := e.Changes()
+	if err != nil {
+		return err.Error()
+	}
+	var parts []string
+	for _, c := range changes {
+		switch c.Field {
+		case "addr", "network", "name":
+			continue
+		}
+		b, a := truncate(c.Before), truncate(c.After)
+		switch {
+		case b == "":
+			parts = append(parts, fmt.Sprintf("%s: %s", c.Field, a))
+		case a == "":
+			parts = append(parts, fmt.Sprintf("%s: %s → ∅", c.Field, b))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %s → %s", c.Field, b, a))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func truncate(s string) string {
+	if r := []rune(s); len(r) > summaryWidth {
+		return string(r[:summaryWidth-1]) + "…"
+	}
+	return s
+}
+
+// fields flattens a JSON object to its top-level values rendered as strings.
+// Custom fields are flattened one level further to field.<name> and lists of
+// strings such as tags are joined.
+func fields(raw json.RawMessage) (map[string]string, error) {
+	out := make(map[string]string)
+	if len(raw) == 0 {
+		return out, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("decode audit state: %s", err)
+	}
+	for k, v := range m {
+		var custom map[string]string
+		if k == "fields" && json.Unmarshal(v, &custom) == nil {
+			for name, value := range custom {
+				out["field."+name] = value
+			}
+			continue
+		}
+		var s string
+		var list []string
+		switch {
+		case json.Unmarshal(v, &s) == nil:
+		case json.Unmarshal(v, &list) == nil:
+			s = strings.Join(list, ", ")
+		default:
+			s = string(v)
+		}
+		out[k] = s
+	}
+	return out, nil
+}
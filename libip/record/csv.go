@@ -0,0 +1,43 @@
+package record
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strings"
+)
+
+// WriteCSV writes records in the column layout read by binip ip import, with a
+// field.<name> column for every custom field of the networks
+func WriteCSV(w io.Writer, networks []Network, records []Record) error {
+	var custom []string
+	for _, n := range networks {
+		for _, f := range n.Fields {
+			if !slices.Contains(custom, f.Name) {
+				custom = append(custom, f.Name)
+			}
+		}
+	}
+	cw := csv.NewWriter(w)
+	header := []string{"network", "address", "state", "hostname", "mac", "owner", "description", "tags"}
+	for _, name := range custom {
+		header = append(header, CustomColumn+name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{r.Network, r.ID(), r.State.String(), r.Hostname, r.MAC, r.Owner, r.Description, strings.Join(r.Tags, " ")}
+		for _, name := range custom {
+			row = append(row, r.Fields[name])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// CustomColumn prefixes the CSV columns holding custom fields
+const CustomColumn = "field."
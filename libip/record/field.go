@@ -0,0 +1,189 @@
+package record
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is the type of a custom record field, which decides the values it
+// accepts
+type FieldType uint8
+
+const (
+	FieldString FieldType = iota
+	FieldInt
+	FieldEnum
+	FieldDate
+	FieldURL
+)
+
+// FieldTypes lists every custom field type
+var FieldTypes = []FieldType{FieldString, FieldInt, FieldEnum, FieldDate, FieldURL}
+
+func (t FieldType) String() string {
+	switch t {
+	case FieldString:
+		return "string"
+	case FieldInt:
+		return "int"
+	case FieldEnum:
+		return "enum"
+	case FieldDate:
+		return "date"
+	case FieldURL:
+		return "url"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseFieldType parses a field type name as returned by FieldType.String
+func ParseFieldType(s string) (FieldType, error) {
+	for _, t := range FieldTypes {
+		if strings.EqualFold(s, t.String()) {
+			return t, nil
+		}
+	}
+	return FieldString, fmt.Errorf("unknown field type %q", s)
+}
+
+func (t FieldType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *FieldType) UnmarshalText(text []byte) error {
+	ft, err := ParseFieldType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = ft
+	return nil
+}
+
+// FieldDef defines a custom field records of a network may carry
+type FieldDef struct {
+	Name string    `json:"name"`
+	Type FieldType `json:"type"`
+	// Values are the choices of an enum field
+	Values []string `json:"values,omitempty"`
+}
+
+// fieldName is the form of custom field names and tags: a letter or digit
+// followed by letters, digits and a few separators
+var fieldName = regexp.MustCompile(`^[a-z0-9][a-z0-9._:/=-]*$`)
+
+// Validate checks the definition itself
+func (f FieldDef) Validate() error {
+	if !fieldName.MatchString(f.Name) {
+		return fmt.Errorf("invalid field name %q: use lower-case letters, digits and . _ : / = -", f.Name)
+	}
+	if f.Type == FieldEnum && len(f.Values) == 0 {
+		return fmt.Errorf("enum field %s needs at least one value", f.Name)
+	}
+	if f.Type != FieldEnum && len(f.Values) > 0 {
+		return fmt.Errorf("only enum fields take values, %s is %s", f.Name, f.Type)
+	}
+	return nil
+}
+
+// Check validates a value against the field's type
+func (f FieldDef) Check(v string) error {
+	switch f.Type {
+	case FieldInt:
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("%s: %q is not an integer", f.Name, v)
+		}
+	case FieldEnum:
+		if !slices.Contains(f.Values, v) {
+			return fmt.Errorf("%s: %q is not one of %s", f.Name, v, strings.Join(f.Values, ", "))
+		}
+	case FieldDate:
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			return fmt.Errorf("%s: %q is not a date (YYYY-MM-DD)", f.Name, v)
+		}
+	case FieldURL:
+		u, err := url.Parse(v)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s: %q is not an absolute URL", f.Name, v)
+		}
+	}
+	return nil
+}
+
+func (f FieldDef) String() string {
+	if f.Type == FieldEnum {
+		return fmt.Sprintf("%s (%s: %s)", f.Name, f.Type, strings.Join(f.Values, ", "))
+	}
+	return fmt.Sprintf("%s (%s)", f.Name, f.Type)
+}
+
+// Field returns the network's definition of a custom field
+func (n Network) Field(name string) (FieldDef, bool) {
+	for _, f := range n.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return FieldDef{}, false
+}
+
+// ValidateRecord checks a record's tags and custom fields against the
+// network's field schema
+func (n Network) ValidateRecord(r Record) error {
+	for _, t := range r.Tags {
+		if !fieldName.MatchString(t) {
+			return fmt.Errorf("invalid tag %q: use lower-case letters, digits and . _ : / = -", t)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Fields)) {
+		f, ok := n.Field(name)
+		if !ok {
+			return fmt.Errorf("network %s has no field %q", n.Name, name)
+		}
+		if err := f.Check(r.Fields[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseTags splits a comma or space separated list into normalised tags
+func ParseTags(s string) []string {
+	return NormaliseTags(strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }))
+}
+
+// NormaliseTags lower-cases, sorts and de-duplicates tags
+func NormaliseTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return slices.Compact(out)
+}
+
+// SetField sets a custom field, removing it when v is empty
+func (r *Record) SetField(name, v string) {
+	if v == "" {
+		delete(r.Fields, name)
+		return
+	}
+	if r.Fields == nil {
+		r.Fields = make(map[string]string)
+	}
+	r.Fields[name] = v
+}
+
+// HasTag reports whether the record carries a tag
+func (r Record) HasTag(tag string) bool {
+	return slices.Contains(r.Tags, tag)
+}
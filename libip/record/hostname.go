@@ -0,0 +1,49 @@
+package record
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// placeholder matches {name} and {name:0W} in a template, and anything else
+// in braces so that it can be reported
+var placeholder = regexp.MustCompile(`\{([a-z_]+)(?::0(\d+))?\}|\{[^}]*\}`)
+
+// expandTemplate substitutes placeholders in a template. Names in nums are
+// numbers, zero-padded to W digits by {name:0W}; names in vars are text.
+func expandTemplate(template string, vars map[string]string, nums map[string]int) (string, error) {
+	var err error
+	out := placeholder.ReplaceAllStringFunc(template, func(p string) string {
+		m := placeholder.FindStringSubmatch(p)
+		if n, ok := nums[m[1]]; ok {
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, n)
+		}
+		if v, ok := vars[m[1]]; ok && m[2] == "" {
+			return v
+		}
+		if err == nil {
+			err = fmt.Errorf("unknown placeholder %s", p)
+		}
+		return p
+	})
+	return out, err
+}
+
+// ExpandHostname substitutes n into a hostname template. {n} is replaced by
+// the number and {n:02} by the number zero-padded to two digits; a template
+// without a placeholder is returned unchanged.
+func ExpandHostname(template string, n int) (string, error) {
+	out, err := expandTemplate(template, nil, map[string]int{"n": n})
+	if err != nil {
+		return "", fmt.Errorf("hostname template %q: %s (use {n} or {n:02})", template, err)
+	}
+	return out, nil
+}
+
+// HostnameNumbered reports whether a hostname template has a placeholder, so
+// expanding it for different numbers gives different hostnames
+func HostnameNumbered(template string) bool {
+	return placeholder.MatchString(template)
+}
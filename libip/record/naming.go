@@ -0,0 +1,152 @@
+package record
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// Naming is a network's hostname policy. Hostnames must match Pattern, or
+// when it is empty the pattern derived from Template.
+//
+// Template placeholders are {index}, the record's number, {role}, the role
+// of the range holding the address, and any of Vars, e.g.
+// {site}-{role}-{index:03}.{domain}. Vars["role"] stands in for addresses
+// outside a role range.
+type Naming struct {
+	Template string            `json:"template,omitempty"`
+	Pattern  string            `json:"pattern,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
+}
+
+// IsZero reports whether no policy is set
+func (nm Naming) IsZero() bool {
+	return nm.Template == "" && nm.Pattern == "" && len(nm.Vars) == 0
+}
+
+// maxIndex bounds the search for an unused hostname
+const maxIndex = 1 << 16
+
+// Validate checks that the template's placeholders are known and the pattern
+// compiles
+func (nm Naming) Validate() error {
+	if nm.Template != "" {
+		for _, m := range placeholder.FindAllStringSubmatch(nm.Template, -1) {
+			switch name := m[1]; {
+			case name == "index":
+			case name == "role" && m[2] == "":
+			case name == "" || m[2] != "":
+				return fmt.Errorf("naming template %q: invalid placeholder %s", nm.Template, m[0])
+			default:
+				if _, ok := nm.Vars[name]; !ok {
+					return fmt.Errorf("naming template %q: no value for {%s}", nm.Template, name)
+				}
+			}
+		}
+	}
+	if _, err := nm.Regexp(); err != nil {
+		return fmt.Errorf("naming pattern: %s", err)
+	}
+	return nil
+}
+
+// Regexp returns the pattern hostnames must match, or nil if there is no
+// policy. Hostnames are matched ignoring case.
+func (nm Naming) Regexp() (*regexp.Regexp, error) {
+	pattern := nm.Pattern
+	if pattern == "" {
+		if nm.Template == "" {
+			return nil, nil
+		}
+		pattern = nm.templatePattern()
+	}
+	return regexp.Compile("(?i)^(?:" + pattern + ")$")
+}
+
+// templatePattern derives a pattern from the template
+func (nm Naming) templatePattern() string {
+	roles := make([]string, 0, len(Roles)+1)
+	for _, r := range Roles {
+		roles = append(roles, regexp.QuoteMeta(r.String()))
+	}
+	if v, ok := nm.Vars["role"]; ok {
+		roles = append(roles, regexp.QuoteMeta(v))
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholder.FindAllStringSubmatchIndex(nm.Template, -1) {
+		b.WriteString(regexp.QuoteMeta(nm.Template[last:loc[0]]))
+		last = loc[1]
+		name := nm.Template[loc[2]:loc[3]]
+		switch {
+		case name == "index" && loc[4] >= 0:
+			b.WriteString(`\d{` + nm.Template[loc[4]:loc[5]] + `,}`)
+		case name == "index":
+			b.WriteString(`\d+`)
+		case name == "role":
+			b.WriteString("(?:" + strings.Join(roles, "|") + ")")
+		default:
+			b.WriteString(regexp.QuoteMeta(nm.Vars[name]))
+		}
+	}
+	b.WriteString(regexp.QuoteMeta(nm.Template[last:]))
+	return b.String()
+}
+
+// String describes the policy for messages
+func (nm Naming) String() string {
+	if nm.Pattern != "" {
+		return "/" + nm.Pattern + "/"
+	}
+	return nm.Template
+}
+
+// CheckHostname reports a hostname that breaks the network's naming policy.
+// An empty hostname is always accepted.
+func (n Network) CheckHostname(hostname string) error {
+	re, err := n.Naming.Regexp()
+	if err != nil || re == nil || hostname == "" {
+		return err
+	}
+	if !re.MatchString(hostname) {
+		return fmt.Errorf("hostname %q does not follow the naming policy of %s (%s)", hostname, n.Name, n.Naming)
+	}
+	return nil
+}
+
+// Hostname expands the naming template for an address and index
+func (n Network) Hostname(addr netip.Addr, index int) (string, error) {
+	if n.Naming.Template == "" {
+		return "", fmt.Errorf("network %s has no naming template", n.Name)
+	}
+	vars := maps.Clone(n.Naming.Vars)
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	if role := n.RoleOf(addr); role != RoleNone {
+		vars["role"] = role.String()
+	}
+	return expandTemplate(n.Naming.Template, vars, map[string]int{"index": index})
+}
+
+// SuggestHostname returns the template's hostname for an address with the
+// lowest index not yet used
+func (n Network) SuggestHostname(addr netip.Addr, used func(hostname string) bool) (string, bool) {
+	for i := 1; i < maxIndex; i++ {
+		h, err := n.Hostname(addr, i)
+		if err != nil {
+			return "", false
+		}
+		if !used(strings.ToLower(h)) {
+			return h, true
+		}
+		if !strings.Contains(n.Naming.Template, "{index") {
+			// Every index gives the same name
+			return "", false
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,154 @@
+package record
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// DefaultNetwork is the name given to the network created during onboarding
+const DefaultNetwork = "default"
+
+// Network is a named prefix that records are allocated from
+type Network struct {
+	Name   string       `json:"name"`
+	Prefix netip.Prefix `json:"prefix"`
+	// Quarantine is how long a released address is held back before the
+	// allocator may hand it out again.
+	Quarantine time.Duration `json:"quarantine"`
+	// Ranges lay out the roles of address spans within the prefix
+	Ranges []Range `json:"ranges,omitempty"`
+	// Provider marks the addresses a cloud platform holds back as reserved
+	Provider Provider `json:"provider,omitempty"`
+	// Metadata holds free-form key/value details, such as the template the
+	// network was created from
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Fields define the custom fields records of the network may carry
+	Fields []FieldDef `json:"fields,omitempty"`
+	// Naming is the policy hostnames of the network's records follow
+	Naming Naming `json:"naming,omitzero"`
+}
+
+// Validate checks that the ranges fit the prefix and do not overlap, and that
+// the custom fields and naming policy are well defined
+func (n Network) Validate() error {
+	if err := n.Naming.Validate(); err != nil {
+		return fmt.Errorf("network %s: %s", n.Name, err)
+	}
+	for i, f := range n.Fields {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("network %s: %s", n.Name, err)
+		}
+		for _, o := range n.Fields[:i] {
+			if o.Name == f.Name {
+				return fmt.Errorf("network %s: duplicate field name %s", n.Name, f.Name)
+			}
+		}
+	}
+	for i, rg := range n.Ranges {
+		if rg.Name == "" {
+			return fmt.Errorf("network %s: range name required", n.Name)
+		}
+		if !n.Contains(rg.First) || !n.Contains(rg.Last) {
+			return fmt.Errorf("network %s: range %s (%s) is outside %s", n.Name, rg.Name, rg, n.Prefix)
+		}
+		for _, o := range n.Ranges[:i] {
+			if o.Name == rg.Name {
+				return fmt.Errorf("network %s: duplicate range name %s", n.Name, rg.Name)
+			}
+			if o.Overlaps(rg) {
+				return fmt.Errorf("network %s: range %s (%s) overlaps %s (%s)", n.Name, rg.Name, rg, o.Name, o)
+			}
+		}
+	}
+	return nil
+}
+
+// RangeOf returns the range containing addr, if any. Addresses held back by
+// the network's provider report a single-address reserved range.
+func (n Network) RangeOf(addr netip.Addr) (Range, bool) {
+	if n.ProviderReserved(addr) {
+		return Range{Name: n.Provider.String() + "-reserved", Role: RoleReserved, First: addr, Last: addr}, true
+	}
+	for _, rg := range n.Ranges {
+		if rg.Contains(addr) {
+			return rg, true
+		}
+	}
+	return Range{}, false
+}
+
+// RoleOf returns the role of the range containing addr
+func (n Network) RoleOf(addr netip.Addr) Role {
+	rg, _ := n.RangeOf(addr)
+	return rg.Role
+}
+
+// RangesWithRole returns the ranges assigned the given role, in address order
+func (n Network) RangesWithRole(role Role) []Range {
+	var out []Range
+	for _, rg := range n.Ranges {
+		if rg.Role == role {
+			out = append(out, rg)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].First.Less(out[j].First) })
+	return out
+}
+
+// Contains reports whether addr belongs to the network
+func (n Network) Contains(addr netip.Addr) bool {
+	return n.Prefix.Contains(addr)
+}
+
+// Hosts returns the first and last assignable addresses of the network. For
+// IPv4 prefixes shorter than /31 the network and broadcast addresses are
+// excluded.
+func (n Network) Hosts() (first, last netip.Addr) {
+	p := n.Prefix.Masked()
+	first = p.Addr()
+	last = LastAddr(p)
+	if first.Is4() && p.Bits() < 31 {
+		first = first.Next()
+		last = last.Prev()
+	}
+	return first, last
+}
+
+// LastAddr returns the highest address contained in the prefix
+func LastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	bits := p.Bits()
+	for i := range b {
+		hostBits := len(b)*8 - bits - (len(b)-1-i)*8
+		switch {
+		case hostBits >= 8:
+			b[i] = 0xff
+		case hostBits > 0:
+			b[i] |= byte(1<<hostBits - 1)
+		}
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// AddrAt returns the address off places after the start of the prefix. It
+// reports false if the offset falls outside the prefix.
+func AddrAt(p netip.Prefix, off *big.Int) (netip.Addr, bool) {
+	size := new(big.Int).Lsh(big.NewInt(1), uint(p.Addr().BitLen()-p.Bits()))
+	if off.Sign() < 0 || off.Cmp(size) >= 0 {
+		return netip.Addr{}, false
+	}
+	start := p.Masked().Addr().AsSlice()
+	base := new(big.Int).SetBytes(start)
+	b := base.Add(base, off).FillBytes(make([]byte, len(start)))
+	return netip.AddrFromSlice(b)
+}
+
+// AddrOffset returns how many places addr lies after the start of the prefix
+func AddrOffset(p netip.Prefix, addr netip.Addr) *big.Int {
+	start := new(big.Int).SetBytes(p.Masked().Addr().AsSlice())
+	return new(big.Int).Sub(new(big.Int).SetBytes(addr.AsSlice()), start)
+}
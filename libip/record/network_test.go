@@ -0,0 +1,30 @@
+package record
+
+import (
+	"math/big"
+	"net/netip"
+	"testing"
+)
+
+func TestAddrAt(t *testing.T) {
+	tests := []struct {
+		prefix string
+		off    int64
+		want   string
+		ok     bool
+	}{
+		{"10.0.0.0/24", 0, "10.0.0.0", true},
+		{"10.0.0.0/24", 255, "10.0.0.255", true},
+		{"10.0.0.0/24", 256, "", false},
+		{"10.0.0.0/24", -1, "", false},
+		{"10.0.0.7/24", 5, "10.0.0.5", true},
+		{"fd00::/120", 255, "fd00::ff", true},
+		{"fd00::/120", 1 << 48, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := AddrAt(netip.MustParsePrefix(tt.prefix), big.NewInt(tt.off))
+		if ok != tt.ok || ok && got.String() != tt.want {
+			t.Errorf("AddrAt(%s, %d) = %s, %t; want %s, %t", tt.prefix, tt.off, got, ok, tt.want, tt.ok)
+		}
+	}
+}
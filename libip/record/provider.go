@@ -0,0 +1,152 @@
+package record
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"strings"
+)
+
+// Provider identifies a cloud platform whose subnets hold back addresses for
+// the platform's own use
+type Provider uint8
+
+const (
+	ProviderNone Provider = iota
+	ProviderAWS
+	ProviderAzure
+	ProviderGCP
+)
+
+// Providers lists every provider with a reservation policy
+var Providers = []Provider{ProviderAWS, ProviderAzure, ProviderGCP}
+
+// ReservationPolicy is the number of addresses a provider reserves at the
+// start and end of every subnet
+type ReservationPolicy struct {
+	Head int
+	Tail int
+}
+
+func (p Provider) String() string {
+	switch p {
+	case ProviderNone:
+		return ""
+	case ProviderAWS:
+		return "aws"
+	case ProviderAzure:
+		return "azure"
+	case ProviderGCP:
+		return "gcp"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseProvider parses a provider name as returned by Provider.String
+func ParseProvider(s string) (Provider, error) {
+	if s == "" || strings.EqualFold(s, "none") {
+		return ProviderNone, nil
+	}
+	for _, p := range Providers {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return ProviderNone, fmt.Errorf("unknown provider %q", s)
+}
+
+// Policy returns the addresses the provider reserves in each subnet
+func (p Provider) Policy() ReservationPolicy {
+	switch p {
+	case ProviderAWS, ProviderAzure:
+		return ReservationPolicy{Head: 4, Tail: 1}
+	case ProviderGCP:
+		return ReservationPolicy{Head: 2, Tail: 2}
+	default:
+		return ReservationPolicy{}
+	}
+}
+
+func (p Provider) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Provider) UnmarshalText(text []byte) error {
+	provider, err := ParseProvider(string(text))
+	if err != nil {
+		return err
+	}
+	*p = provider
+	return nil
+}
+
+// ProviderReserved reports whether addr is held back by the network's cloud
+// provider
+func (n Network) ProviderReserved(addr netip.Addr) bool {
+	if n.Provider == ProviderNone || !n.Contains(addr) {
+		return false
+	}
+	policy := n.Provider.Policy()
+	head := n.Prefix.Masked().Addr()
+	for i := 0; i < policy.Head; i++ {
+		if addr == head {
+			return true
+		}
+		head = head.Next()
+	}
+	tail := LastAddr(n.Prefix)
+	for i := 0; i < policy.Tail; i++ {
+		if addr == tail {
+			return true
+		}
+		tail = tail.Prev()
+	}
+	return false
+}
+
+// UsableSpan returns the first and last addresses available for records once
+// the provider's reservations are taken out of the assignable hosts
+func (n Network) UsableSpan() (first, last netip.Addr) {
+	if n.Provider == ProviderNone {
+		return n.Hosts()
+	}
+	policy := n.Provider.Policy()
+	first, last = n.Prefix.Masked().Addr(), LastAddr(n.Prefix)
+	for i := 0; i < policy.Head; i++ {
+		first = first.Next()
+	}
+	for i := 0; i < policy.Tail; i++ {
+		last = last.Prev()
+	}
+	return first, last
+}
+
+// Size returns the number of addresses in the prefix, saturating at the
+// largest uint64
+func (n Network) Size() uint64 {
+	hostBits := n.Prefix.Addr().BitLen() - n.Prefix.Bits()
+	if hostBits >= 64 {
+		return math.MaxUint64
+	}
+	return 1 << hostBits
+}
+
+// UsableHosts returns the number of addresses available for records once the
+// provider's reservations, or the IPv4 network and broadcast addresses, are
+// taken out
+func (n Network) UsableHosts() uint64 {
+	size := n.Size()
+	var held uint64
+	switch {
+	case n.Provider != ProviderNone:
+		policy := n.Provider.Policy()
+		held = uint64(policy.Head + policy.Tail)
+	case n.Prefix.Addr().Is4() && n.Prefix.Bits() < 31:
+		held = 2
+	}
+	if held >= size {
+		return 0
+	}
+	return size - held
+}
@@ -0,0 +1,74 @@
+package record
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Record is a single address tracked within a network
+type Record struct {
+	Addr        netip.Addr `json:"addr"`
+	Network     string     `json:"network"`
+	State       State      `json:"state"`
+	Hostname    string     `json:"hostname,omitempty"`
+	MAC         string     `json:"mac,omitempty"`
+	Owner       string     `json:"owner,omitempty"`
+	Description string     `json:"description,omitempty"`
+	// Tags are free-form labels and Fields the values of the network's
+	// custom fields
+	Tags    []string          `json:"tags,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Created time.Time         `json:"created"`
+	Updated time.Time         `json:"updated"`
+	// StateChanged is when the record last moved between lifecycle states,
+	// used to work out when a quarantine period has elapsed.
+	StateChanged time.Time `json:"state_changed"`
+}
+
+// ID returns the identifier used to address the record within its network
+func (r Record) ID() string {
+	return r.Addr.String()
+}
+
+// Equal reports whether two records hold the same values
+func (r Record) Equal(o Record) bool {
+	return r.Addr == o.Addr && r.Network == o.Network && r.State == o.State &&
+		r.Hostname == o.Hostname && r.MAC == o.MAC && r.Owner == o.Owner &&
+		r.Description == o.Description && slices.Equal(r.Tags, o.Tags) &&
+		maps.Equal(r.Fields, o.Fields) && r.Created.Equal(o.Created) &&
+		r.Updated.Equal(o.Updated) && r.StateChanged.Equal(o.StateChanged)
+}
+
+// SetState moves the record to a new lifecycle state, enforcing the allowed
+// transitions. Leaving quarantine additionally requires the network's
+// quarantine period to have elapsed.
+func (r *Record) SetState(to State, n Network, now time.Time) error {
+	if err := r.State.CanTransition(to); err != nil {
+		return err
+	}
+	if r.State == Quarantined && !r.QuarantineElapsed(n, now) {
+		return fmt.Errorf("%s is quarantined until %s", r.Addr, r.QuarantineEnds(n).Format(time.RFC3339))
+	}
+	r.State = to
+	r.StateChanged = now
+	r.Updated = now
+	return nil
+}
+
+// QuarantineEnds returns when the record may be returned to the free pool
+func (r Record) QuarantineEnds(n Network) time.Time {
+	return r.StateChanged.Add(n.Quarantine)
+}
+
+// QuarantineElapsed reports whether a quarantined record may be reused
+func (r Record) QuarantineElapsed(n Network, now time.Time) bool {
+	return r.State == Quarantined && !now.Before(r.QuarantineEnds(n))
+}
+
+// Available reports whether the allocator may hand out this record's address
+func (r Record) Available(n Network, now time.Time) bool {
+	return r.State == Free || r.QuarantineElapsed(n, now)
+}
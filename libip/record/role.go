@@ -0,0 +1,120 @@
+package record
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Role describes what a range of addresses within a network is used for
+type Role uint8
+
+const (
+	RoleNone Role = iota
+	RoleGateway
+	RoleInfra
+	RoleDHCPPool
+	RoleStatic
+	RoleReserved
+)
+
+// Roles lists every assignable role
+var Roles = []Role{RoleGateway, RoleInfra, RoleDHCPPool, RoleStatic, RoleReserved}
+
+func (r Role) String() string {
+	switch r {
+	case RoleNone:
+		return ""
+	case RoleGateway:
+		return "gateway"
+	case RoleInfra:
+		return "infra"
+	case RoleDHCPPool:
+		return "dhcp-pool"
+	case RoleStatic:
+		return "static"
+	case RoleReserved:
+		return "reserved"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseRole parses a role name as returned by Role.String
+func ParseRole(s string) (Role, error) {
+	for _, r := range Roles {
+		if strings.EqualFold(s, r.String()) {
+			return r, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", s)
+}
+
+// Allocatable reports whether the default allocator may hand out addresses
+// from a range of this role. Other roles are only used when asked for by name.
+func (r Role) Allocatable() bool {
+	return r == RoleNone || r == RoleStatic
+}
+
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Role) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*r = RoleNone
+		return nil
+	}
+	role, err := ParseRole(string(text))
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// Range is a named, inclusive span of addresses within a network
+type Range struct {
+	Name  string     `json:"name"`
+	Role  Role       `json:"role"`
+	First netip.Addr `json:"first"`
+	Last  netip.Addr `json:"last"`
+}
+
+// ParseRange parses "first-last" or a single address into a range
+func ParseRange(name string, role Role, s string) (Range, error) {
+	lo, hi, found := strings.Cut(s, "-")
+	first, err := netip.ParseAddr(strings.TrimSpace(lo))
+	if err != nil {
+		return Range{}, err
+	}
+	last := first
+	if found {
+		last, err = netip.ParseAddr(strings.TrimSpace(hi))
+		if err != nil {
+			return Range{}, err
+		}
+	}
+	rg := Range{Name: name, Role: role, First: first, Last: last}
+	if last.Less(first) {
+		return rg, fmt.Errorf("range %s: %s is before %s", name, last, first)
+	}
+	return rg, nil
+}
+
+// Contains reports whether addr falls within the range
+func (rg Range) Contains(addr netip.Addr) bool {
+	return rg.First.Compare(addr) <= 0 && addr.Compare(rg.Last) <= 0
+}
+
+// Overlaps reports whether the two ranges share any address
+func (rg Range) Overlaps(o Range) bool {
+	return rg.First.Compare(o.Last) <= 0 && o.First.Compare(rg.Last) <= 0
+}
+
+func (rg Range) String() string {
+	if rg.First == rg.Last {
+		return rg.First.String()
+	}
+	return rg.First.String() + "-" + rg.Last.String()
+}
@@ -0,0 +1,16 @@
+package record
+
+import "time"
+
+// Sample is a daily snapshot of a network's utilisation
+type Sample struct {
+	Date     time.Time `json:"date"`
+	Used     uint64    `json:"used"`
+	Reserved uint64    `json:"reserved"`
+	Usable   uint64    `json:"usable"`
+}
+
+// Occupied returns the addresses unavailable to the allocator on that day
+func (s Sample) Occupied() uint64 {
+	return s.Used + s.Reserved
+}
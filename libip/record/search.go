@@ -0,0 +1,7 @@
+package record
+
+// SavedSearch is a named record query expression
+type SavedSearch struct {
+	Name  string `json:"name"`
+	Where string `json:"where"`
+}
@@ -0,0 +1,31 @@
+package record
+
+import "net/netip"
+
+// Session is where a user left the TUI, restored on the next launch
+type Session struct {
+	Network string `json:"network"`
+	// Mode is the view open on exit: list, map, dashboard or detail
+	Mode string `json:"mode,omitempty"`
+	// Record is the record selected in the list or shown in the detail view
+	Record      string         `json:"record,omitempty"`
+	Search      string         `json:"search,omitempty"`
+	StateFilter string         `json:"state_filter,omitempty"`
+	SavedSearch string         `json:"saved_search,omitempty"`
+	Columns     bool           `json:"columns,omitempty"`
+	MapColour   string         `json:"map_colour,omitempty"`
+	MapStack    []netip.Prefix `json:"map_stack,omitempty"`
+	Bookmarks   []Bookmark     `json:"bookmarks,omitempty"`
+	// Recent are the records most recently opened, newest first
+	Recent []Bookmark `json:"recent,omitempty"`
+}
+
+// Bookmark points at a record of a network
+type Bookmark struct {
+	Network string `json:"network"`
+	Record  string `json:"record"`
+}
+
+func (b Bookmark) String() string {
+	return b.Network + " " + b.Record
+}
@@ -0,0 +1,85 @@
+package record
+
+import (
+	"fmt"
+	"strings"
+)
+
+// State is the lifecycle state of an address record
+type State uint8
+
+const (
+	Free State = iota
+	Reserved
+	Allocated
+	Deprecated
+	Quarantined
+)
+
+// States lists every lifecycle state in display order
+var States = []State{Reserved, Allocated, Deprecated, Quarantined, Free}
+
+// transitions holds the allowed lifecycle moves for each state. Anything not
+// listed here is rejected by CanTransition.
+var transitions = map[State][]State{
+	Free:        {Reserved, Allocated},
+	Reserved:    {Allocated, Free},
+	Allocated:   {Deprecated, Quarantined, Reserved},
+	Deprecated:  {Allocated, Quarantined},
+	Quarantined: {Free},
+}
+
+func (s State) String() string {
+	switch s {
+	case Free:
+		return "free"
+	case Reserved:
+		return "reserved"
+	case Allocated:
+		return "allocated"
+	case Deprecated:
+		return "deprecated"
+	case Quarantined:
+		return "quarantined"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseState parses a state name as returned by State.String
+func ParseState(s string) (State, error) {
+	for _, st := range States {
+		if strings.EqualFold(s, st.String()) {
+			return st, nil
+		}
+	}
+	return Free, fmt.Errorf("unknown state %q", s)
+}
+
+// CanTransition reports whether a record may move from s to the given state
+func (s State) CanTransition(to State) error {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot transition from %s to %s", s, to)
+}
+
+// InUse reports whether an address in this state is unavailable to the allocator
+func (s State) InUse() bool {
+	return s != Free
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *State) UnmarshalText(text []byte) error {
+	st, err := ParseState(string(text))
+	if err != nil {
+		return err
+	}
+	*s = st
+	return nil
+}
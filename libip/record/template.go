@@ -0,0 +1,201 @@
+package record
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Template is a reusable network layout. Offsets are relative to the prefix it
+// is applied to so one template scales across prefix sizes: "N" counts from
+// the network address, "-N" counts back from the last address (-1 being the
+// last address itself) and "N%" is a fraction of the prefix size.
+type Template struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Bits        int               `json:"bits,omitempty"` // suggested prefix length, 0 for any
+	Provider    Provider          `json:"provider,omitempty"`
+	Ranges      []TemplateRange   `json:"ranges,omitempty"`
+	Records     []TemplateRecord  `json:"records,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// TemplateRange is a role range expressed as offsets into the prefix
+type TemplateRange struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TemplateRecord is a placeholder record created when a template is applied
+type TemplateRecord struct {
+	At          string `json:"at"`
+	Hostname    string `json:"hostname,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// BuiltinTemplates are seeded into every new database
+var BuiltinTemplates = []Template{
+	{
+		Name:        "office-/24",
+		Description: "Office LAN: gateway on .1, infrastructure .2-.9, DHCP pool .100-.199 on a /24",
+		Bits:        24,
+		Ranges: []TemplateRange{
+			{Name: "gateway", Role: RoleGateway, From: "1", To: "1"},
+			{Name: "infra", Role: RoleInfra, From: "2", To: "9"},
+			{Name: "static", Role: RoleStatic, From: "10", To: "99"},
+			{Name: "dhcp", Role: RoleDHCPPool, From: "100", To: "-57"},
+		},
+		Records: []TemplateRecord{
+			{At: "1", Hostname: "gw", Description: "default gateway"},
+		},
+		Metadata: map[string]string{"kind": "office"},
+	},
+	{
+		Name:        "k8s-node-/26",
+		Description: "Kubernetes node subnet: gateway, control-plane VIPs, nodes and a reserved tail",
+		Bits:        26,
+		Ranges: []TemplateRange{
+			{Name: "gateway", Role: RoleGateway, From: "1", To: "1"},
+			{Name: "control-plane", Role: RoleInfra, From: "2", To: "4"},
+			{Name: "nodes", Role: RoleStatic, From: "5", To: "75%"},
+			{Name: "spare", Role: RoleReserved, From: "-15", To: "-2"},
+		},
+		Records: []TemplateRecord{
+			{At: "1", Hostname: "gw", Description: "default gateway"},
+			{At: "2", Hostname: "k8s-api", Description: "API server VIP"},
+		},
+		Metadata: map[string]string{"kind": "k8s"},
+	},
+	{
+		Name:        "aws-vpc-subnet",
+		Description: "AWS VPC subnet: router, DNS and future-use addresses reserved",
+		Provider:    ProviderAWS,
+		Records: []TemplateRecord{
+			{At: "1", Hostname: "vpc-router", Description: "VPC router"},
+			{At: "2", Hostname: "amazon-dns", Description: "Amazon-provided DNS"},
+		},
+		Metadata: map[string]string{"kind": "cloud"},
+	},
+}
+
+// Validate checks that the template can be applied: its ranges have roles
+// and, once resolved, neither overlap nor fall outside the prefix, and every
+// offset is valid. It is applied to a prefix of the suggested length, or
+// without one to each length in turn until one fits.
+func (t Template) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name required")
+	}
+	for _, tr := range t.Ranges {
+		if tr.Role == RoleNone {
+			return fmt.Errorf("template %s range %s: role required", t.Name, tr.Name)
+		}
+	}
+	if t.Bits != 0 {
+		base := netip.IPv4Unspecified()
+		if t.Bits > 32 {
+			base = netip.IPv6Unspecified()
+		}
+		p, err := base.Prefix(t.Bits)
+		if err != nil {
+			return fmt.Errorf("template %s: invalid prefix length %d", t.Name, t.Bits)
+		}
+		_, _, err = t.Apply(Network{Name: t.Name, Prefix: p})
+		return err
+	}
+
+	var err error
+	for bits := 126; bits >= 0; bits-- {
+		p := netip.PrefixFrom(netip.IPv6Unspecified(), bits)
+		if _, _, err = t.Apply(Network{Name: t.Name, Prefix: p}); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Apply resolves the template against a concrete network, returning the
+// network with ranges and metadata filled in and the placeholder records to
+// create
+func (t Template) Apply(n Network) (Network, []Record, error) {
+	p := n.Prefix.Masked()
+	if n.Provider == ProviderNone {
+		n.Provider = t.Provider
+	}
+	for _, tr := range t.Ranges {
+		first, err := ResolveOffset(p, tr.From)
+		if err != nil {
+			return n, nil, fmt.Errorf("template %s range %s: %s", t.Name, tr.Name, err)
+		}
+		last, err := ResolveOffset(p, tr.To)
+		if err != nil {
+			return n, nil, fmt.Errorf("template %s range %s: %s", t.Name, tr.Name, err)
+		}
+		if last.Less(first) {
+			return n, nil, fmt.Errorf("template %s range %s does not fit %s", t.Name, tr.Name, p)
+		}
+		n.Ranges = append(n.Ranges, Range{Name: tr.Name, Role: tr.Role, First: first, Last: last})
+	}
+	if n.Metadata == nil {
+		n.Metadata = make(map[string]string)
+	}
+	for k, v := range t.Metadata {
+		n.Metadata[k] = v
+	}
+	n.Metadata["template"] = t.Name
+	if err := n.Validate(); err != nil {
+		return n, nil, err
+	}
+
+	var records []Record
+	for _, tr := range t.Records {
+		addr, err := ResolveOffset(p, tr.At)
+		if err != nil {
+			return n, nil, fmt.Errorf("template %s record %s: %s", t.Name, tr.Hostname, err)
+		}
+		records = append(records, Record{
+			Addr:        addr,
+			Network:     n.Name,
+			State:       Reserved,
+			Hostname:    tr.Hostname,
+			Description: tr.Description,
+		})
+	}
+	return n, records, nil
+}
+
+// ResolveOffset turns a template offset into an address within the prefix
+func ResolveOffset(p netip.Prefix, offset string) (netip.Addr, error) {
+	p = p.Masked()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(p.Addr().BitLen()-p.Bits()))
+
+	off := new(big.Int)
+	switch s := strings.TrimSpace(offset); {
+	case strings.HasSuffix(s, "%"):
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || pct < 0 || pct > 100 {
+			return netip.Addr{}, fmt.Errorf("invalid percentage offset %q", offset)
+		}
+		f := new(big.Float).Mul(new(big.Float).SetInt(size), big.NewFloat(pct/100))
+		f.Int(off)
+		if off.Cmp(size) >= 0 {
+			off.Sub(size, big.NewInt(1))
+		}
+	default:
+		if _, ok := off.SetString(s, 10); !ok {
+			return netip.Addr{}, fmt.Errorf("invalid offset %q", offset)
+		}
+		if off.Sign() < 0 {
+			off.Add(size, off)
+		}
+	}
+	if off.Sign() < 0 || off.Cmp(size) >= 0 {
+		return netip.Addr{}, fmt.Errorf("offset %s is outside %s", offset, p)
+	}
+	addr, _ := AddrAt(p, off)
+	return addr, nil
+}
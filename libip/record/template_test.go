@@ -0,0 +1,55 @@
+package record
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       Template
+		wantErr string
+	}{
+		{name: "fits its size", t: Template{Name: "t", Bits: 24, Ranges: []TemplateRange{
+			{Name: "a", Role: RoleStatic, From: "10", To: "99"},
+			{Name: "b", Role: RoleDHCPPool, From: "100", To: "-2"},
+		}}},
+		{name: "fits some size", t: Template{Name: "t", Ranges: []TemplateRange{
+			{Name: "a", Role: RoleStatic, From: "1000", To: "-1"},
+		}}},
+		{name: "no name", t: Template{}, wantErr: "name required"},
+		{name: "no role", t: Template{Name: "t", Ranges: []TemplateRange{{Name: "a", From: "1", To: "2"}}}, wantErr: "role required"},
+		{name: "overlap", t: Template{Name: "t", Ranges: []TemplateRange{
+			{Name: "a", Role: RoleStatic, From: "1", To: "10"},
+			{Name: "b", Role: RoleInfra, From: "5", To: "20"},
+		}}, wantErr: "overlaps"},
+		{name: "bad offset", t: Template{Name: "t", Ranges: []TemplateRange{{Name: "a", Role: RoleStatic, From: "x", To: "2"}}}, wantErr: "invalid offset"},
+		{name: "bad record offset", t: Template{Name: "t", Records: []TemplateRecord{{At: "150%"}}}, wantErr: "invalid percentage"},
+		{name: "too big for its size", t: Template{Name: "t", Bits: 26, Ranges: []TemplateRange{
+			{Name: "a", Role: RoleStatic, From: "10", To: "99"},
+		}}, wantErr: "outside"},
+		{name: "inverted", t: Template{Name: "t", Bits: 24, Ranges: []TemplateRange{
+			{Name: "a", Role: RoleStatic, From: "-2", To: "5"},
+		}}, wantErr: "does not fit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.t.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate = %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+	for _, b := range BuiltinTemplates {
+		if err := b.Validate(); err != nil {
+			t.Errorf("builtin %s: %s", b.Name, err)
+		}
+	}
+}
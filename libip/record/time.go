@@ -0,0 +1,23 @@
+package record
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseTime accepts a duration back from now ("24h"), a local date, a local
+// date and time, or an RFC 3339 timestamp
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.DateOnly, time.DateTime, "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: want a duration ago, date, date and time or RFC 3339 timestamp", s)
+}
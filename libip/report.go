@@ -0,0 +1,25 @@
+package libip
+
+import (
+	"fmt"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/report"
+)
+
+func ReportUtilisation(c *config.Config, format string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	usages, err := report.Load(d, c.Alerts.ForecastModel)
+	if err != nil {
+		return err
+	}
+	out, err := report.Render(usages, format, c.Thresholds)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
@@ -0,0 +1,144 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+const day = 24 * time.Hour
+
+// maxForecastDays is the furthest ahead a forecast is made, about a century
+const maxForecastDays = 36500
+
+// Forecast models supported by Project
+const (
+	Linear      = "linear"
+	Exponential = "exponential"
+)
+
+// Forecast projects when a network will run out of usable addresses
+type Forecast struct {
+	Model string `json:"model"`
+	// Rate is addresses per day for the linear model and the daily growth
+	// fraction for the exponential model
+	Rate       float64   `json:"rate"`
+	DaysToFull float64   `json:"days_to_full"`
+	FullOn     time.Time `json:"full_on"`
+}
+
+func (f Forecast) String() string {
+	if f.DaysToFull < 1 {
+		return "full within a day"
+	}
+	return fmt.Sprintf("at current rate, full in %.0f days", f.DaysToFull)
+}
+
+// Project fits the sampled utilisation with the given model and extrapolates
+// to the day the network is full. It reports false when there are too few
+// samples, utilisation is not growing, or the network would not be full
+// within maxForecastDays.
+func Project(samples []record.Sample, model string, now time.Time) (Forecast, bool) {
+	if len(samples) < 2 {
+		return Forecast{}, false
+	}
+	last := samples[len(samples)-1]
+	if last.Usable == 0 {
+		return Forecast{}, false
+	}
+	if last.Occupied() >= last.Usable {
+		return Forecast{Model: model, FullOn: last.Date}, true
+	}
+
+	origin := samples[0].Date
+	var xs, ys []float64
+	for _, s := range samples {
+		y := float64(s.Occupied())
+		if model == Exponential {
+			if y <= 0 {
+				continue
+			}
+			y = math.Log(y)
+		}
+		xs = append(xs, s.Date.Sub(origin).Hours()/24)
+		ys = append(ys, y)
+	}
+	slope, ok := fitSlope(xs, ys)
+	if !ok || slope <= 0 {
+		return Forecast{}, false
+	}
+
+	f := Forecast{Model: model, Rate: slope}
+	remaining := float64(last.Usable - last.Occupied())
+	var days float64
+	switch model {
+	case Exponential:
+		f.Rate = math.Exp(slope) - 1
+		days = math.Log(float64(last.Usable)/float64(last.Occupied())) / slope
+	default:
+		f.Model = Linear
+		days = remaining / slope
+	}
+	if math.IsNaN(days) || days > maxForecastDays {
+		// Too slow to be worth forecasting, and past what a Duration holds
+		return Forecast{}, false
+	}
+	f.FullOn = last.Date.Add(time.Duration(days * float64(day)))
+	f.DaysToFull = math.Max(0, days-now.Sub(last.Date).Hours()/24)
+	return f, true
+}
+
+// fitSlope returns the least-squares slope of ys against xs
+func fitSlope(xs, ys []float64) (float64, bool) {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0, false
+	}
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, false
+	}
+	return (n*sxy - sx*sy) / den, true
+}
+
+// RecordSamples stores today's utilisation of every network in the history
+func RecordSamples(d *db.Db, usages []Usage, now time.Time) error {
+	for _, u := range usages {
+		if u.Kind != "network" {
+			continue
+		}
+		s := record.Sample{Date: now, Used: u.Used, Reserved: u.Reserved, Usable: u.Usable}
+		if err := d.PutSample(u.Network, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AttachForecasts projects the exhaustion date of every network from its
+// stored history
+func AttachForecasts(d *db.Db, usages []Usage, model string, now time.Time) error {
+	for i, u := range usages {
+		if u.Kind != "network" {
+			continue
+		}
+		samples, err := d.ListSamples(u.Network)
+		if err != nil {
+			return err
+		}
+		if f, ok := Project(samples, model, now); ok {
+			usages[i].Forecast = &f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+func TestProject(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := func(usable uint64, used ...uint64) []record.Sample {
+		var out []record.Sample
+		for i, u := range used {
+			out = append(out, record.Sample{Date: start.AddDate(0, 0, i), Used: u, Usable: usable})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		samples []record.Sample
+		model   string
+		ok      bool
+		days    float64 // expected days to full from the last sample
+	}{
+		{name: "one sample", samples: series(254, 10), model: Linear},
+		{name: "flat", samples: series(254, 10, 10, 10), model: Linear},
+		{name: "shrinking", samples: series(254, 30, 20, 10), model: Linear},
+		{name: "growing", samples: series(254, 10, 20, 30), model: Linear, ok: true, days: 22.4},
+		{name: "already full", samples: series(254, 250, 254), model: Linear, ok: true},
+		{name: "near-zero slope", samples: series(65534, 10, 10, 11), model: Linear},
+		{name: "exponential growing", samples: series(1000, 10, 20, 40), model: Exponential, ok: true, days: 4.6},
+		{name: "exponential near-zero slope", samples: series(1<<62, 1000, 1000, 1001), model: Exponential},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.samples[len(tt.samples)-1].Date
+			f, ok := Project(tt.samples, tt.model, now)
+			if ok != tt.ok {
+				t.Fatalf("ok = %t, want %t (forecast %+v)", ok, tt.ok, f)
+			}
+			if !ok {
+				return
+			}
+			if f.DaysToFull < 0 || f.DaysToFull-tt.days > 0.1 || tt.days-f.DaysToFull > 0.1 {
+				t.Errorf("DaysToFull = %.2f, want %.1f", f.DaysToFull, tt.days)
+			}
+			if f.FullOn.Before(now) && tt.days > 0 {
+				t.Errorf("FullOn %s is before the last sample", f.FullOn)
+			}
+		})
+	}
+}
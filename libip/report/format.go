@@ -0,0 +1,98 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// Formats lists the output formats accepted by Render
+var Formats = []string{"table", "json", "markdown"}
+
+// Render formats utilisation rows as a table, JSON or Markdown
+func Render(usages []Usage, format string, t config.Thresholds) (string, error) {
+	switch format {
+	case "table", "":
+		return Table(usages, t).Render(), nil
+	case "json":
+		out, err := json.MarshalIndent(usages, "", "  ")
+		return string(out), err
+	case "markdown":
+		return Markdown(usages), nil
+	default:
+		return "", fmt.Errorf("unknown format %q, want one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Table renders utilisation rows with percentage bars. Rows over a threshold
+// are drawn in the error style.
+func Table(usages []Usage, t config.Thresholds) *table.Table {
+	tbl := styles.StyledTable().
+		Headers("node", "span", "used", "reserved", "free", "utilisation", "largest free", "frag", "full in")
+	for _, u := range usages {
+		tbl.Row(
+			indent(u),
+			u.Span,
+			fmt.Sprint(u.Used),
+			fmt.Sprint(u.Reserved),
+			fmt.Sprint(u.Free),
+			fmt.Sprintf("%s %5.1f%%", Bar(u.Percent(), 10), u.Percent()),
+			fmt.Sprint(u.LargestFree),
+			fmt.Sprintf("%.2f", u.Fragmentation),
+			fullIn(u),
+		)
+	}
+
+	tbl.StyleFunc(func(row, col int) lipgloss.Style {
+		switch {
+		case row == table.HeaderRow:
+			return styles.TableHeaderStyle
+		case row < len(usages) && usages[row].Exceeds(t):
+			return styles.ErrorStyle.Padding(0, 1)
+		default:
+			return styles.CellStyle
+		}
+	})
+	return tbl
+}
+
+// Markdown renders utilisation rows as a GitHub-flavoured Markdown table
+func Markdown(usages []Usage) string {
+	var b strings.Builder
+	b.WriteString("| node | span | used | reserved | free | utilisation | largest free | fragmentation | full in |\n")
+	b.WriteString("|---|---|---:|---:|---:|---:|---:|---:|---:|\n")
+	for _, u := range usages {
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %.1f%% | %d | %.2f | %s |\n",
+			strings.Repeat("&nbsp;&nbsp;", u.Depth)+u.Name, u.Span, u.Used, u.Reserved, u.Free,
+			u.Percent(), u.LargestFree, u.Fragmentation, fullIn(u))
+	}
+	return b.String()
+}
+
+// Bar renders a percentage as a bar of the given width
+func Bar(percent float64, width int) string {
+	filled := int(percent/100*float64(width) + 0.5)
+	filled = max(0, min(filled, width))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// fullIn renders the forecast days until a network is full
+func fullIn(u Usage) string {
+	if u.Forecast == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.0fd", u.Forecast.DaysToFull)
+}
+
+func indent(u Usage) string {
+	name := u.Name
+	if u.Kind == "network" && u.Prefix.IsValid() {
+		name = fmt.Sprintf("%s %s", u.Name, u.Prefix)
+	}
+	return strings.Repeat("  ", u.Depth) + name
+}
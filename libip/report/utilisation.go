@@ -0,0 +1,232 @@
+package report
+
+import (
+	"math"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+)
+
+// Usage is the utilisation of one node of the subnet tree: a network, or a
+// role range within a network
+type Usage struct {
+	Kind    string       `json:"kind"` // "network" or "range"
+	Name    string       `json:"name"`
+	Role    string       `json:"role,omitempty"`
+	Network string       `json:"network"`
+	Prefix  netip.Prefix `json:"prefix,omitzero"`
+	Span    string       `json:"span"`
+	Depth   int          `json:"depth"`
+
+	Usable   uint64 `json:"usable"`
+	Used     uint64 `json:"used"`
+	Reserved uint64 `json:"reserved"`
+	Free     uint64 `json:"free"`
+
+	LargestFree      uint64     `json:"largest_free"`
+	LargestFreeStart netip.Addr `json:"largest_free_start,omitzero"`
+	// Fragmentation is 1 - largest free block / total free, so 0 when all
+	// free space is contiguous and approaching 1 as it splinters
+	Fragmentation float64 `json:"fragmentation"`
+
+	// Forecast is set for networks with enough history to project growth
+	Forecast *Forecast `json:"forecast,omitempty"`
+}
+
+// Percent returns the share of usable addresses that are used or reserved
+func (u Usage) Percent() float64 {
+	if u.Usable == 0 {
+		return 0
+	}
+	return float64(u.Used+u.Reserved) / float64(u.Usable) * 100
+}
+
+// Exceeds reports whether the node is over either configured threshold.
+// Gateway and reserved ranges are expected to be full and never exceed.
+func (u Usage) Exceeds(t config.Thresholds) bool {
+	if u.Role == record.RoleGateway.String() || u.Role == record.RoleReserved.String() {
+		return false
+	}
+	return u.Percent() > t.Utilisation || u.Fragmentation > t.Fragmentation
+}
+
+// Tree computes utilisation for every network and its role ranges. Networks
+// whose prefix sits inside another network's are nested beneath it. Nodes are
+// returned depth first with Depth set for indentation.
+func Tree(networks []record.Network, records map[string][]record.Record) []Usage {
+	sorted := append([]record.Network(nil), networks...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i].Prefix, sorted[j].Prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+
+	// Each network's parent is the most specific other network containing it
+	children := make(map[string][]record.Network)
+	var roots []record.Network
+	for i, n := range sorted {
+		parent := ""
+		for _, p := range sorted[:i] {
+			if p.Prefix.Bits() <= n.Prefix.Bits() && p.Prefix.Contains(n.Prefix.Addr()) && p.Name != n.Name {
+				parent = p.Name
+			}
+		}
+		if parent == "" {
+			roots = append(roots, n)
+		} else {
+			children[parent] = append(children[parent], n)
+		}
+	}
+
+	var out []Usage
+	var walk func(n record.Network, depth int)
+	walk = func(n record.Network, depth int) {
+		u := NetworkUsage(n, records[n.Name])
+		u.Depth = depth
+		out = append(out, u)
+		for _, rg := range sortedRanges(n) {
+			ru := RangeUsage(n, rg, records[n.Name])
+			ru.Depth = depth + 1
+			out = append(out, ru)
+		}
+		for _, c := range children[n.Name] {
+			walk(c, depth+1)
+		}
+	}
+	for _, n := range roots {
+		walk(n, 0)
+	}
+	return out
+}
+
+// NetworkUsage computes the utilisation of a whole network
+func NetworkUsage(n record.Network, records []record.Record) Usage {
+	first, last := n.UsableSpan()
+	u := spanUsage(first, last, records)
+	u.Kind, u.Name, u.Network, u.Prefix = "network", n.Name, n.Name, n.Prefix
+	return u
+}
+
+// RangeUsage computes the utilisation of a role range within a network
+func RangeUsage(n record.Network, rg record.Range, records []record.Record) Usage {
+	first, last := n.UsableSpan()
+	if rg.First.Compare(first) > 0 {
+		first = rg.First
+	}
+	if rg.Last.Compare(last) < 0 {
+		last = rg.Last
+	}
+	u := spanUsage(first, last, records)
+	u.Kind, u.Name, u.Role, u.Network = "range", rg.Name, rg.Role.String(), n.Name
+	return u
+}
+
+func sortedRanges(n record.Network) []record.Range {
+	ranges := append([]record.Range(nil), n.Ranges...)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].First.Less(ranges[j].First) })
+	return ranges
+}
+
+// spanUsage tallies the records within first..last and measures the free
+// space between them
+func spanUsage(first, last netip.Addr, records []record.Record) Usage {
+	u := Usage{Span: first.String() + "-" + last.String()}
+	if !first.IsValid() || last.Less(first) {
+		u.Span = ""
+		return u
+	}
+	u.Usable = distance(first, last)
+	if u.Usable < math.MaxUint64 {
+		u.Usable++
+	}
+
+	var occupied []netip.Addr
+	for _, r := range records {
+		if r.Addr.Less(first) || last.Less(r.Addr) {
+			continue
+		}
+		switch r.State {
+		case record.Allocated, record.Deprecated:
+			u.Used++
+		case record.Reserved, record.Quarantined:
+			u.Reserved++
+		default:
+			continue
+		}
+		occupied = append(occupied, r.Addr)
+	}
+	u.Free = u.Usable - u.Used - u.Reserved
+
+	// Walk the gaps between occupied addresses to find the largest free run
+	sort.Slice(occupied, func(i, j int) bool { return occupied[i].Less(occupied[j]) })
+	cursor := first
+	consider := func(start netip.Addr, size uint64) {
+		if size > u.LargestFree {
+			u.LargestFree, u.LargestFreeStart = size, start
+		}
+	}
+	for _, addr := range occupied {
+		if cursor.Less(addr) {
+			consider(cursor, distance(cursor, addr))
+		}
+		cursor = addr.Next()
+	}
+	if cursor.IsValid() && cursor.Compare(last) <= 0 {
+		size := distance(cursor, last)
+		if size < math.MaxUint64 {
+			size++
+		}
+		consider(cursor, size)
+	}
+
+	if u.Free > 0 {
+		u.Fragmentation = 1 - float64(u.LargestFree)/float64(u.Free)
+	}
+	return u
+}
+
+// distance returns the number of addresses from a up to but not including b,
+// saturating at the largest uint64
+func distance(a, b netip.Addr) uint64 {
+	d := record.AddrOffset(netip.PrefixFrom(a, a.BitLen()), b)
+	if !d.IsUint64() {
+		return math.MaxUint64
+	}
+	return d.Uint64()
+}
+
+// Load reads every network and its records from the database and computes
+// the utilisation tree, forecasting each network's exhaustion date from its
+// history with the given model. It does not write to the database.
+func Load(d *db.Db, model string) ([]Usage, error) {
+	usages, err := Usages(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := AttachForecasts(d, usages, model, time.Now()); err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
+
+// Usages reads every network and its records from the database and computes
+// the utilisation tree
+func Usages(d *db.Db) ([]Usage, error) {
+	networks, err := d.ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string][]record.Record)
+	for _, n := range networks {
+		if records[n.Name], err = d.ListRecords(n.Name); err != nil {
+			return nil, err
+		}
+	}
+	return Tree(networks, records), nil
+}
@@ -0,0 +1,69 @@
+package libip
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/query"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+)
+
+func SearchList(c *config.Config) error {
+	d := db.New(c)
+	defer d.Close()
+
+	searches, err := d.ListSearches()
+	if err != nil {
+		return err
+	}
+	t := styles.StyledTable().Headers("name", "where")
+	for _, s := range searches {
+		t.Row(s.Name, s.Where)
+	}
+	fmt.Println(t.Render())
+	return nil
+}
+
+func SearchSave(c *config.Config, name, where string) error {
+	if _, err := parseWhere(where, query.Records); err != nil {
+		return err
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	return d.SaveSearch(record.SavedSearch{Name: name, Where: where})
+}
+
+func SearchRemove(c *config.Config, name string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	return d.DeleteSearch(name)
+}
+
+// parseWhere parses a --where expression, pointing at the fault on error
+func parseWhere(where string, t query.Target) (*query.Query, error) {
+	q, err := query.Parse(where, t)
+	if err != nil {
+		return nil, errors.New(query.Explain(where, err))
+	}
+	return q, nil
+}
+
+// resolveWhere combines a --where expression with a saved search
+func resolveWhere(d *db.Db, where, saved string) (string, error) {
+	if saved == "" {
+		return where, nil
+	}
+	s, err := d.GetSearch(saved)
+	if err != nil {
+		return "", err
+	}
+	if where == "" {
+		return s.Where, nil
+	}
+	return fmt.Sprintf("(%s) and (%s)", s.Where, where), nil
+}
@@ -5,36 +5,88 @@ import (
 	"github.com/charmbracelet/lipgloss/table"
 )
 
+// The styles are built from the active theme by Use; see theme.go
 var (
-	purple       = lipgloss.Color("99")
-	gray         = lipgloss.Color("245")
-	lightGray    = lipgloss.Color("241")
-	red          = lipgloss.Color("196")
-	green        = lipgloss.Color("42")
-	lightGreen   = lipgloss.Color("112")
-	adaptiveGray = lipgloss.AdaptiveColor{Light: "241", Dark: "245"}
-
-	tableHeaderStyle = lipgloss.NewStyle().Foreground(purple).Bold(true).Align(lipgloss.Center)
-	HeaderStyle      = lipgloss.NewStyle().Background(purple).Bold(true).Align(lipgloss.Left)
+	TableHeaderStyle lipgloss.Style
+	HeaderStyle      lipgloss.Style
 	FooterStyle      = lipgloss.NewStyle().
 				Align(lipgloss.Center, lipgloss.Bottom)
-	ErrorStyle   = lipgloss.NewStyle().Foreground(red).Bold(true)
-	StatusStyle  = lipgloss.NewStyle().Foreground(green)
-	AccentStyle  = lipgloss.NewStyle().Foreground(lightGreen)
-	InfoStyle    = lipgloss.NewStyle().Foreground(adaptiveGray)
-	cellStyle    = lipgloss.NewStyle().Padding(0, 1)
-	oddRowStyle  = cellStyle.Foreground(gray)
-	evenRowStyle = cellStyle.Foreground(lightGray)
+	ErrorStyle   lipgloss.Style
+	WarnStyle    lipgloss.Style
+	StatusStyle  lipgloss.Style
+	AccentStyle  lipgloss.Style
+	InfoStyle    lipgloss.Style
+	CellStyle    = lipgloss.NewStyle().Padding(0, 1)
+	oddRowStyle  lipgloss.Style
+	evenRowStyle lipgloss.Style
+	borderColor  lipgloss.TerminalColor
+
+	SelectedStyle lipgloss.Style
+	// ChangedStyle briefly marks rows changed by another process
+	ChangedStyle lipgloss.Style
+	// MatchStyle marks the characters matched by a search
+	MatchStyle lipgloss.Style
+
+	// Lifecycle state badges
+	ReservedBadge    lipgloss.Style
+	AllocatedBadge   lipgloss.Style
+	DeprecatedBadge  lipgloss.Style
+	QuarantinedBadge lipgloss.Style
+	FreeBadge        lipgloss.Style
+
+	// Range role colours, keyed by role name
+	roleStyles map[string]lipgloss.Style
+
+	MapCursorStyle lipgloss.Style
+
+	// PaneStyle separates a side pane from the main view
+	PaneStyle lipgloss.Style
+	// BoxStyle frames popups such as the command palette
+	BoxStyle lipgloss.Style
 )
 
+func init() {
+	Use(darkTheme)
+}
+
+// StateCell returns the map cell style for a lifecycle state
+func StateCell(state string) lipgloss.Style {
+	return cell(active.States[state])
+}
+
+// RoleCell returns the map cell style for a range role
+func RoleCell(role string) lipgloss.Style {
+	return cell(active.Roles[role])
+}
+
+// EmptyCell is the map cell style for unused address space
+func EmptyCell() lipgloss.Style {
+	return cell(nil)
+}
+
+func cell(c lipgloss.TerminalColor) lipgloss.Style {
+	if c == nil {
+		return lipgloss.NewStyle().Background(active.Empty)
+	}
+	return lipgloss.NewStyle().Background(c).Reverse(active.Mono)
+}
+
+// RoleStyle returns the style used to render addresses of a range role
+func RoleStyle(role string) lipgloss.Style {
+	if s, ok := roleStyles[role]; ok {
+		return s
+	}
+	return InfoStyle
+}
+
 func StyledTable() *table.Table {
 	return table.New().
 		Border(lipgloss.NormalBorder()).
-		BorderStyle(lipgloss.NewStyle().Foreground(purple)).
+		BorderStyle(lipgloss.NewStyle().Foreground(borderColor)).
 		StyleFunc(func(row, col int) lipgloss.Style {
 			switch {
 			case row == table.HeaderRow:
-				return tableHeaderStyle
+				return TableHeaderStyle
 			case row%2 == 0:
 				return evenRowStyle
 			default:
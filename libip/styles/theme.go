@@ -0,0 +1,364 @@
+package styles
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/termenv"
+)
+
+// Theme is the set of colours the styles are built from
+type Theme struct {
+	Name string
+	// Accent colours headers, table borders and the selection
+	Accent lipgloss.TerminalColor
+	// HeaderText is the text colour on the accent background
+	HeaderText lipgloss.TerminalColor
+	// BadgeText is the text colour on state badges
+	BadgeText lipgloss.TerminalColor
+	// Muted and MutedAlt alternate between table rows
+	Muted    lipgloss.TerminalColor
+	MutedAlt lipgloss.TerminalColor
+	Info     lipgloss.TerminalColor
+	Error    lipgloss.TerminalColor
+	Warn     lipgloss.TerminalColor
+	Success  lipgloss.TerminalColor
+	// Highlight marks active searches and secondary emphasis
+	Highlight lipgloss.TerminalColor
+	// Match marks the characters matched by a search
+	Match lipgloss.TerminalColor
+	// Cursor is the text colour of the address map cursor
+	Cursor lipgloss.TerminalColor
+	// Empty is the background of unused address space in the map
+	Empty lipgloss.TerminalColor
+	// States and Roles colour lifecycle states and range roles by name
+	States map[string]lipgloss.TerminalColor
+	Roles  map[string]lipgloss.TerminalColor
+	// Bold emboldens coloured text for legibility
+	Bold bool
+	// Mono themes carry no colour; the selection and used map cells are
+	// shown in reverse video instead
+	Mono bool
+}
+
+var darkTheme = Theme{
+	Name:      "dark",
+	Accent:    lipgloss.Color("99"),
+	BadgeText: lipgloss.Color("0"),
+	Muted:     lipgloss.Color("245"),
+	MutedAlt:  lipgloss.Color("241"),
+	Info:      lipgloss.AdaptiveColor{Light: "241", Dark: "245"},
+	Error:     lipgloss.Color("196"),
+	Warn:      lipgloss.Color("214"),
+	Success:   lipgloss.Color("42"),
+	Highlight: lipgloss.Color("112"),
+	Match:     lipgloss.Color("214"),
+	Cursor:    lipgloss.Color("231"),
+	Empty:     lipgloss.AdaptiveColor{Light: "254", Dark: "236"},
+	States: map[string]lipgloss.TerminalColor{
+		"reserved":    lipgloss.Color("214"),
+		"allocated":   lipgloss.Color("42"),
+		"deprecated":  lipgloss.Color("245"),
+		"quarantined": lipgloss.Color("204"),
+		"free":        lipgloss.Color("39"),
+	},
+	Roles: map[string]lipgloss.TerminalColor{
+		"gateway":   lipgloss.Color("170"),
+		"infra":     lipgloss.Color("39"),
+		"dhcp-pool": lipgloss.Color("214"),
+		"static":    lipgloss.Color("112"),
+		"reserved":  lipgloss.Color("245"),
+	},
+}
+
+// lightTheme uses darker shades that stay readable on a light background
+var lightTheme = Theme{
+	Name:       "light",
+	Accent:     lipgloss.Color("55"),
+	HeaderText: lipgloss.Color("231"),
+	BadgeText:  lipgloss.Color("231"),
+	Muted:      lipgloss.Color("238"),
+	MutedAlt:   lipgloss.Color("243"),
+	Info:       lipgloss.Color("242"),
+	Error:      lipgloss.Color("160"),
+	Warn:       lipgloss.Color("130"),
+	Success:    lipgloss.Color("28"),
+	Highlight:  lipgloss.Color("64"),
+	Match:      lipgloss.Color("166"),
+	Cursor:     lipgloss.Color("16"),
+	Empty:      lipgloss.Color("254"),
+	States: map[string]lipgloss.TerminalColor{
+		"reserved":    lipgloss.Color("166"),
+		"allocated":   lipgloss.Color("28"),
+		"deprecated":  lipgloss.Color("244"),
+		"quarantined": lipgloss.Color("162"),
+		"free":        lipgloss.Color("25"),
+	},
+	Roles: map[string]lipgloss.TerminalColor{
+		"gateway":   lipgloss.Color("127"),
+		"infra":     lipgloss.Color("25"),
+		"dhcp-pool": lipgloss.Color("130"),
+		"static":    lipgloss.Color("64"),
+		"reserved":  lipgloss.Color("244"),
+	},
+}
+
+// highContrastTheme uses the terminal's bright base colours in bold
+var highContrastTheme = Theme{
+	Name:       "high-contrast",
+	Accent:     lipgloss.Color("11"),
+	HeaderText: lipgloss.Color("0"),
+	BadgeText:  lipgloss.Color("0"),
+	Muted:      lipgloss.Color("15"),
+	MutedAlt:   lipgloss.Color("7"),
+	Info:       lipgloss.Color("15"),
+	Error:      lipgloss.Color("9"),
+	Warn:       lipgloss.Color("11"),
+	Success:    lipgloss.Color("10"),
+	Highlight:  lipgloss.Color("14"),
+	Match:      lipgloss.Color("11"),
+	Cursor:     lipgloss.Color("0"),
+	Empty:      lipgloss.Color("7"),
+	States: map[string]lipgloss.TerminalColor{
+		"reserved":    lipgloss.Color("11"),
+		"allocated":   lipgloss.Color("10"),
+		"deprecated":  lipgloss.Color("7"),
+		"quarantined": lipgloss.Color("13"),
+		"free":        lipgloss.Color("14"),
+	},
+	Roles: map[string]lipgloss.TerminalColor{
+		"gateway":   lipgloss.Color("13"),
+		"infra":     lipgloss.Color("14"),
+		"dhcp-pool": lipgloss.Color("11"),
+		"static":    lipgloss.Color("10"),
+		"reserved":  lipgloss.Color("7"),
+	},
+	Bold: true,
+}
+
+// colourBlindTheme uses the Okabe-Ito palette, which never relies on telling
+// red from green: success is blue and errors are vermilion
+var colourBlindTheme = Theme{
+	Name:       "colour-blind",
+	Accent:     lipgloss.Color("#0072B2"),
+	HeaderText: lipgloss.Color("#FFFFFF"),
+	BadgeText:  lipgloss.Color("#000000"),
+	Muted:      lipgloss.Color("245"),
+	MutedAlt:   lipgloss.Color("241"),
+	Info:       lipgloss.AdaptiveColor{Light: "241", Dark: "245"},
+	Error:      lipgloss.Color("#D55E00"),
+	Warn:       lipgloss.Color("#E69F00"),
+	Success:    lipgloss.Color("#56B4E9"),
+	Highlight:  lipgloss.Color("#009E73"),
+	Match:      lipgloss.Color("#F0E442"),
+	Cursor:     lipgloss.Color("#FFFFFF"),
+	Empty:      lipgloss.AdaptiveColor{Light: "254", Dark: "236"},
+	States: map[string]lipgloss.TerminalColor{
+		"reserved":    lipgloss.Color("#E69F00"),
+		"allocated":   lipgloss.Color("#56B4E9"),
+		"deprecated":  lipgloss.Color("#999999"),
+		"quarantined": lipgloss.Color("#CC79A7"),
+		"free":        lipgloss.Color("#F0E442"),
+	},
+	Roles: map[string]lipgloss.TerminalColor{
+		"gateway":   lipgloss.Color("#CC79A7"),
+		"infra":     lipgloss.Color("#0072B2"),
+		"dhcp-pool": lipgloss.Color("#E69F00"),
+		"static":    lipgloss.Color("#009E73"),
+		"reserved":  lipgloss.Color("#999999"),
+	},
+}
+
+// monoTheme is used when NO_COLOR is set
+var monoTheme = Theme{
+	Name:       "mono",
+	Accent:     lipgloss.NoColor{},
+	HeaderText: lipgloss.NoColor{},
+	BadgeText:  lipgloss.NoColor{},
+	Muted:      lipgloss.NoColor{},
+	MutedAlt:   lipgloss.NoColor{},
+	Info:       lipgloss.NoColor{},
+	Error:      lipgloss.NoColor{},
+	Warn:       lipgloss.NoColor{},
+	Success:    lipgloss.NoColor{},
+	Highlight:  lipgloss.NoColor{},
+	Match:      lipgloss.NoColor{},
+	Cursor:     lipgloss.NoColor{},
+	Empty:      lipgloss.NoColor{},
+	States: map[string]lipgloss.TerminalColor{
+		"reserved":    lipgloss.NoColor{},
+		"allocated":   lipgloss.NoColor{},
+		"deprecated":  lipgloss.NoColor{},
+		"quarantined": lipgloss.NoColor{},
+		"free":        lipgloss.NoColor{},
+	},
+	Roles: map[string]lipgloss.TerminalColor{
+		"gateway":   lipgloss.NoColor{},
+		"infra":     lipgloss.NoColor{},
+		"dhcp-pool": lipgloss.NoColor{},
+		"static":    lipgloss.NoColor{},
+		"reserved":  lipgloss.NoColor{},
+	},
+	Mono: true,
+}
+
+// stateNames and roleNames are the keys of Theme.States and Theme.Roles
+var (
+	stateNames = []string{"reserved", "allocated", "deprecated", "quarantined", "free"}
+	roleNames  = []string{"gateway", "infra", "dhcp-pool", "static", "reserved"}
+)
+
+var themes = map[string]Theme{
+	darkTheme.Name:         darkTheme,
+	lightTheme.Name:        lightTheme,
+	highContrastTheme.Name: highContrastTheme,
+	colourBlindTheme.Name:  colourBlindTheme,
+	monoTheme.Name:         monoTheme,
+}
+
+// ThemeNames lists the themes selectable in the config file, including auto
+func ThemeNames() []string {
+	names := []string{"auto"}
+	for n := range themes {
+		names = append(names, n)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// active is the theme the styles were last built from
+var active Theme
+
+// Active returns the theme in use
+func Active() Theme {
+	return active
+}
+
+// noColor records why colour output is off, if it is
+var noColor string
+
+// Load applies the named theme. auto picks dark or light from the terminal's
+// background. NO_COLOR selects the mono theme whatever the name, keeping bold
+// and reverse video on terminals; output that is not a terminal is never
+// styled.
+func Load(name string) error {
+	if name == "" || name == "auto" {
+		name = lightTheme.Name
+		if lipgloss.HasDarkBackground() {
+			name = darkTheme.Name
+		}
+	}
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("theme: unknown theme %q (themes: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	switch {
+	case !term.IsTerminal(os.Stdout.Fd()):
+		noColor = "not a terminal"
+	case os.Getenv("NO_COLOR") != "":
+		noColor = "NO_COLOR is set"
+		t = monoTheme
+		// lipgloss drops every attribute along with colour under NO_COLOR;
+		// keep the attributes the mono theme depends on
+		lipgloss.SetColorProfile(termenv.ANSI)
+	}
+	Use(t)
+	return nil
+}
+
+// Use rebuilds the styles from a theme
+func Use(t Theme) {
+	active = t
+	fg := func(c lipgloss.TerminalColor) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(c).Bold(t.Bold)
+	}
+
+	TableHeaderStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Align(lipgloss.Center)
+	HeaderStyle = lipgloss.NewStyle().Background(t.Accent).Foreground(t.HeaderText).Bold(true).Align(lipgloss.Left).Reverse(t.Mono)
+	ErrorStyle = fg(t.Error).Bold(true)
+	WarnStyle = fg(t.Warn)
+	StatusStyle = fg(t.Success)
+	AccentStyle = fg(t.Highlight)
+	InfoStyle = fg(t.Info)
+	oddRowStyle = CellStyle.Foreground(t.Muted)
+	evenRowStyle = CellStyle.Foreground(t.MutedAlt)
+	borderColor = t.Accent
+
+	SelectedStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Reverse(t.Mono)
+	MatchStyle = lipgloss.NewStyle().Foreground(t.Match).Underline(true)
+	ChangedStyle = lipgloss.NewStyle().Foreground(t.Highlight).Bold(true).Italic(t.Mono)
+
+	badge := lipgloss.NewStyle().Foreground(t.BadgeText).Padding(0, 1).Bold(t.Bold)
+	ReservedBadge = badge.Background(t.States["reserved"])
+	AllocatedBadge = badge.Background(t.States["allocated"])
+	DeprecatedBadge = badge.Background(t.States["deprecated"])
+	QuarantinedBadge = badge.Background(t.States["quarantined"])
+	FreeBadge = badge.Background(t.States["free"])
+
+	roleStyles = make(map[string]lipgloss.Style, len(t.Roles))
+	for role, c := range t.Roles {
+		roleStyles[role] = fg(c)
+	}
+	roleStyles["gateway"] = roleStyles["gateway"].Bold(true)
+
+	MapCursorStyle = lipgloss.NewStyle().Foreground(t.Cursor).Bold(true)
+	PaneStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(t.Accent).
+		PaddingLeft(1)
+	BoxStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Accent).
+		Padding(0, 1)
+}
+
+// Preview renders a sample of every style of the active theme
+func Preview() string {
+	t := active
+	profile := "true colour"
+	switch lipgloss.ColorProfile() {
+	case termenv.ANSI256:
+		profile = "256 colours"
+	case termenv.ANSI:
+		profile = "16 colours"
+	case termenv.Ascii:
+		profile = "none"
+	}
+	if noColor != "" {
+		profile = fmt.Sprintf("none (%s)", noColor)
+	}
+
+	var states, roles, cells []string
+	badges := []lipgloss.Style{ReservedBadge, AllocatedBadge, DeprecatedBadge, QuarantinedBadge, FreeBadge}
+	for i, s := range stateNames {
+		states = append(states, badges[i].Render(s))
+		cells = append(cells, StateCell(s).Render("  "))
+	}
+	for _, r := range roleNames {
+		roles = append(roles, RoleStyle(r).Render(r))
+	}
+	cells = append(cells, EmptyCell().Render("  "), EmptyCell().Inherit(MapCursorStyle).Render("[]"))
+
+	return strings.Join([]string{
+		HeaderStyle.Render(fmt.Sprintf(" Theme: %s ", t.Name)),
+		InfoStyle.Render("colour output: " + profile),
+		"",
+		"states  " + strings.Join(states, " "),
+		"roles   " + strings.Join(roles, " "),
+		"map     " + strings.Join(cells, ""),
+		"text    " + strings.Join([]string{
+			SelectedStyle.Render("▸ selected"),
+			MatchStyle.Render("match"),
+			AccentStyle.Render("search"),
+			InfoStyle.Render("info"),
+			StatusStyle.Render("ok"),
+			WarnStyle.Render("warning"),
+			ErrorStyle.Render("error"),
+		}, " "),
+	}, "\n")
+}
@@ -0,0 +1,73 @@
+package libip
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
+)
+
+func TemplateList(c *config.Config) error {
+	d := db.New(c)
+	defer d.Close()
+
+	templates, err := d.ListTemplates()
+	if err != nil {
+		return err
+	}
+	t := styles.StyledTable().Headers("name", "bits", "description")
+	for _, tmpl := range templates {
+		bits := "any"
+		if tmpl.Bits > 0 {
+			bits = "/" + strconv.Itoa(tmpl.Bits)
+		}
+		t.Row(tmpl.Name, bits, tmpl.Description)
+	}
+	fmt.Println(t.Render())
+	return nil
+}
+
+func TemplateShow(c *config.Config, name string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	tmpl, err := d.GetTemplate(name)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(tmpl, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// TemplateAdd stores a template read from a JSON file in the format printed
+// by TemplateShow
+func TemplateAdd(c *config.Config, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var tmpl record.Template
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return fmt.Errorf("parse template %s: %s", file, err)
+	}
+	d := db.New(c)
+	defer d.Close()
+
+	return d.SaveTemplate(tmpl)
+}
+
+func TemplateRemove(c *config.Config, name string) error {
+	d := db.New(c)
+	defer d.Close()
+
+	return d.DeleteTemplate(name)
+}
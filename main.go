@@ -1,23 +1,39 @@
 package main
 
 import (
+	"time"
+
 	"github.com/alecthomas/kong"
 	"github.com/bakedSpaceTime/binip/libip"
 	"github.com/bakedSpaceTime/binip/libip/config"
+	"github.com/bakedSpaceTime/binip/libip/db"
+	"github.com/bakedSpaceTime/binip/libip/record"
+	"github.com/bakedSpaceTime/binip/libip/styles"
 )
 
 type AppCmd struct {
+	NoMouse bool `help:"Disable mouse support for this session."`
 }
 
 func (a *AppCmd) Run(c *config.Config) error {
+	if a.NoMouse {
+		c.Mouse = false
+	}
 	return libip.App(c)
 }
 
 type Info struct {
+	Theme   string `help:"Preview this theme instead of the configured one."`
+	Palette bool   `help:"Also show the 256-colour palette."`
 }
 
 func (i *Info) Run(c *config.Config) error {
-	return libip.Info(c)
+	if i.Theme != "" {
+		if err := styles.Load(i.Theme); err != nil {
+			return err
+		}
+	}
+	return libip.Info(c, i.Palette)
 }
 
 type Test struct {
@@ -34,20 +50,411 @@ func (r *Reset) Run(c *config.Config) error {
 	return libip.Reset(c)
 }
 
+type NetworkList struct {
+	Where string `short:"w" help:"Only networks matching this expression, e.g. 'prefix in 10.0.0.0/8 and provider == aws'"`
+}
+
+func (n *NetworkList) Run(c *config.Config) error {
+	return libip.NetworkList(c, n.Where)
+}
+
+type NetworkAdd struct {
+	Name       string         `arg:"" help:"Network name"`
+	Prefix     string         `arg:"" help:"Network prefix in CIDR notation"`
+	Template   string         `short:"t" help:"Subnet template to lay the network out with"`
+	Provider   string         `enum:",aws,azure,gcp" default:"" help:"Cloud provider whose reserved addresses apply"`
+	Quarantine *time.Duration `help:"How long released addresses are held before reuse (default: the configured quarantine)"`
+}
+
+func (n *NetworkAdd) Run(c *config.Config) error {
+	return libip.NetworkAdd(c, n.Name, n.Prefix, n.Template, n.Provider, n.Quarantine)
+}
+
+type NetworkProvider struct {
+	Name     string `arg:"" help:"Network name"`
+	Provider string `arg:"" enum:"none,aws,azure,gcp" help:"Cloud provider whose reserved addresses apply"`
+}
+
+func (n *NetworkProvider) Run(c *config.Config) error {
+	return libip.NetworkSetProvider(c, n.Name, n.Provider)
+}
+
+type NetworkQuarantine struct {
+	Name   string        `arg:"" help:"Network name"`
+	Period time.Duration `arg:"" help:"How long released addresses are held before reuse"`
+}
+
+func (n *NetworkQuarantine) Run(c *config.Config) error {
+	return libip.NetworkSetQuarantine(c, n.Name, n.Period)
+}
+
+type NetworkShow struct {
+	Name string `arg:"" help:"Network name"`
+	At   string `help:"Show the network as it stood at this time (date, RFC 3339 or duration ago)"`
+}
+
+func (n *NetworkShow) Run(c *config.Config) error {
+	return libip.NetworkShow(c, n.Name, n.At)
+}
+
+type NetworkRangeAdd struct {
+	Network string `arg:"" help:"Network name"`
+	Name    string `arg:"" help:"Range name"`
+	Role    string `arg:"" enum:"gateway,infra,dhcp-pool,static,reserved" help:"Range role"`
+	Span    string `arg:"" help:"Address or first-last span, e.g. 10.0.4.2-10.0.4.9"`
+}
+
+func (n *NetworkRangeAdd) Run(c *config.Config) error {
+	return libip.NetworkRangeAdd(c, n.Network, n.Name, n.Role, n.Span)
+}
+
+type NetworkRangeRemove struct {
+	Network string `arg:"" help:"Network name"`
+	Name    string `arg:"" help:"Range name"`
+}
+
+func (n *NetworkRangeRemove) Run(c *config.Config) error {
+	return libip.NetworkRangeRemove(c, n.Network, n.Name)
+}
+
+type NetworkFieldAdd struct {
+	Network string   `arg:"" help:"Network name"`
+	Name    string   `arg:"" help:"Field name"`
+	Type    string   `arg:"" enum:"string,int,enum,date,url" help:"Field type"`
+	Values  []string `help:"Choices of an enum field, comma separated"`
+}
+
+func (n *NetworkFieldAdd) Run(c *config.Config) error {
+	return libip.NetworkFieldAdd(c, n.Network, n.Name, n.Type, n.Values)
+}
+
+type NetworkFieldRemove struct {
+	Network string `arg:"" help:"Network name"`
+	Name    string `arg:"" help:"Field name"`
+}
+
+func (n *NetworkFieldRemove) Run(c *config.Config) error {
+	return libip.NetworkFieldRemove(c, n.Network, n.Name)
+}
+
+type NetworkNaming struct {
+	Name     string            `arg:"" help:"Network name"`
+	Template string            `short:"t" help:"Hostname template, e.g. {site}-{role}-{index:03}.{domain}"`
+	Pattern  string            `short:"p" help:"Regular expression hostnames must match; derived from the template when omitted"`
+	Var      map[string]string `help:"Template variable as NAME=VALUE (repeatable)"`
+	Clear    bool              `help:"Remove the naming policy"`
+}
+
+func (n *NetworkNaming) Run(c *config.Config) error {
+	return libip.NetworkSetNaming(c, n.Name, record.Naming{Template: n.Template, Pattern: n.Pattern, Vars: n.Var}, n.Clear)
+}
+
+type NetworkLint struct {
+	Name string `arg:"" optional:"" help:"Network name; every network when omitted"`
+}
+
+func (n *NetworkLint) Run(c *config.Config) error {
+	return libip.NetworkLint(c, n.Name)
+}
+
+type NetworkFieldCmd struct {
+	Add NetworkFieldAdd    `cmd:"" help:"Define a custom record field"`
+	Rm  NetworkFieldRemove `cmd:"" help:"Remove a custom record field and its values"`
+}
+
+type NetworkRangeCmd struct {
+	Add NetworkRangeAdd    `cmd:"" help:"Add a role range to a network"`
+	Rm  NetworkRangeRemove `cmd:"" help:"Remove a role range from a network"`
+}
+
+type NetworkCmd struct {
+	List       NetworkList       `cmd:"" default:"1" help:"List networks"`
+	Show       NetworkShow       `cmd:"" help:"Show a network and its ranges"`
+	Add        NetworkAdd        `cmd:"" help:"Add a network"`
+	Quarantine NetworkQuarantine `cmd:"" help:"Set a network's quarantine period"`
+	Provider   NetworkProvider   `cmd:"" help:"Set a network's cloud provider reservation policy"`
+	Range      NetworkRangeCmd   `cmd:"" help:"Manage role ranges"`
+	Field      NetworkFieldCmd   `cmd:"" help:"Manage custom record fields"`
+	Naming     NetworkNaming     `cmd:"" help:"Set a network's hostname naming policy"`
+	Lint       NetworkLint       `cmd:"" help:"Report hostnames that break the naming policy"`
+}
+
+type TemplateList struct {
+}
+
+func (t *TemplateList) Run(c *config.Config) error {
+	return libip.TemplateList(c)
+}
+
+type TemplateShow struct {
+	Name string `arg:"" help:"Template name"`
+}
+
+func (t *TemplateShow) Run(c *config.Config) error {
+	return libip.TemplateShow(c, t.Name)
+}
+
+type TemplateAdd struct {
+	File string `arg:"" type:"existingfile" help:"JSON template definition"`
+}
+
+func (t *TemplateAdd) Run(c *config.Config) error {
+	return libip.TemplateAdd(c, t.File)
+}
+
+type TemplateRemove struct {
+	Name string `arg:"" help:"Template name"`
+}
+
+func (t *TemplateRemove) Run(c *config.Config) error {
+	return libip.TemplateRemove(c, t.Name)
+}
+
+type TemplateCmd struct {
+	List TemplateList   `cmd:"" default:"1" help:"List subnet templates"`
+	Show TemplateShow   `cmd:"" help:"Print a template as JSON"`
+	Add  TemplateAdd    `cmd:"" help:"Add or replace a template from a JSON file"`
+	Rm   TemplateRemove `cmd:"" help:"Remove a template"`
+}
+
+type IpList struct {
+	State  string `help:"Only show records in this state"`
+	At     string `help:"List records as they stood at this time (date, RFC 3339 or duration ago)"`
+	Where  string `short:"w" help:"Only records matching this expression, e.g. 'state == allocated and ip in 10.0.0.0/20 and field.expires < now+7d'"`
+	Saved  string `short:"s" help:"Only records matching this saved search"`
+	All    bool   `short:"A" help:"List records of every network"`
+	Format string `short:"f" enum:"table,json,csv" default:"table" help:"Output format (table, json, csv)"`
+}
+
+func (i *IpList) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpList(c, ip.Network, i.State, i.At, i.Where, i.Saved, i.All, i.Format)
+}
+
+type IpShow struct {
+	Addr string `arg:"" help:"Address to show"`
+	At   string `help:"Show the record as it stood at this time (date, RFC 3339 or duration ago)"`
+}
+
+func (i *IpShow) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpShow(c, ip.Network, i.Addr, i.At)
+}
+
+type IpFind struct {
+	Hostname string `help:"Find the address holding this hostname"`
+	Mac      string `help:"Find the address holding this MAC address"`
+	Tag      string `help:"Find the addresses carrying this tag"`
+}
+
+func (i *IpFind) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpFind(c, ip.Network, i.Hostname, i.Mac, i.Tag)
+}
+
+type IpAdd struct {
+	Addr        string            `arg:"" help:"Address to add"`
+	State       string            `default:"reserved" help:"Initial lifecycle state"`
+	Hostname    string            `help:"Hostname"`
+	Mac         string            `help:"MAC address"`
+	Owner       string            `help:"Owner"`
+	Description string            `help:"Description"`
+	Tag         []string          `help:"Tag to attach (repeatable)"`
+	Field       map[string]string `help:"Custom field value as NAME=VALUE (repeatable)"`
+}
+
+func (i *IpAdd) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpAdd(c, ip.Network, i.Addr, i.State, record.Record{
+		Hostname:    i.Hostname,
+		MAC:         i.Mac,
+		Owner:       i.Owner,
+		Description: i.Description,
+		Tags:        i.Tag,
+		Fields:      i.Field,
+	})
+}
+
+type IpTag struct {
+	Addr   string   `arg:"" help:"Address to tag"`
+	Tags   []string `arg:"" help:"Tags to add"`
+	Remove bool     `help:"Remove the tags instead"`
+}
+
+func (i *IpTag) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpTag(c, ip.Network, i.Addr, i.Tags, i.Remove)
+}
+
+type IpField struct {
+	Addr   string            `arg:"" help:"Address to change"`
+	Fields map[string]string `arg:"" help:"Custom field values as NAME=VALUE; an empty value clears the field"`
+}
+
+func (i *IpField) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpSetFields(c, ip.Network, i.Addr, i.Fields)
+}
+
+type IpAlloc struct {
+	Hostname   string `help:"Hostname to assign; {n} or {n:02} numbers the hostnames of several addresses"`
+	Role       string `enum:",gateway,infra,dhcp-pool,static,reserved" default:"" help:"Allocate from a range with this role"`
+	Count      int    `short:"c" default:"1" help:"Number of addresses to allocate, all or none"`
+	Contiguous bool   `help:"Allocate one unbroken block of addresses"`
+	Start      int    `default:"1" help:"Number of the first hostname"`
+	DryRun     bool   `help:"Show the addresses that would be assigned without allocating them"`
+}
+
+func (i *IpAlloc) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpAlloc(c, ip.Network, i.Role, db.AllocateRequest{
+		Count:      i.Count,
+		Contiguous: i.Contiguous,
+		Hostname:   i.Hostname,
+		First:      i.Start,
+	}, i.DryRun)
+}
+
+type IpRelease struct {
+	Addr string `arg:"" help:"Address to release"`
+}
+
+func (i *IpRelease) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpRelease(c, ip.Network, i.Addr)
+}
+
+type IpState struct {
+	Addr  string `arg:"" help:"Address to change"`
+	State string `arg:"" enum:"reserved,allocated,deprecated,quarantined,free" help:"New lifecycle state"`
+}
+
+func (i *IpState) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpSetState(c, ip.Network, i.Addr, i.State)
+}
+
+type IpRemove struct {
+	Addr string `arg:"" help:"Address to remove"`
+}
+
+func (i *IpRemove) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpRemove(c, ip.Network, i.Addr)
+}
+
+type IpImport struct {
+	File string `arg:"" type:"existingfile" help:"CSV file with an address header column"`
+}
+
+func (i *IpImport) Run(c *config.Config, ip *IpCmd) error {
+	return libip.IpImport(c, ip.Network, i.File)
+}
+
+type ReportUtilisation struct {
+	Format string `short:"f" enum:"table,json,markdown" default:"table" help:"Output format (table, json, markdown)"`
+}
+
+func (r *ReportUtilisation) Run(c *config.Config) error {
+	return libip.ReportUtilisation(c, r.Format)
+}
+
+type ReportCmd struct {
+	Utilisation ReportUtilisation `cmd:"" help:"Show used, reserved and free addresses per network and range"`
+}
+
+type AlertCheck struct {
+}
+
+func (a *AlertCheck) Run(c *config.Config) error {
+	return libip.AlertCheck(c)
+}
+
+type AlertCmd struct {
+	Check AlertCheck `cmd:"" default:"1" help:"Sample utilisation and fire newly crossed alerts"`
+}
+
+type SearchList struct {
+}
+
+func (s *SearchList) Run(c *config.Config) error {
+	return libip.SearchList(c)
+}
+
+type SearchSave struct {
+	Name  string `arg:"" help:"Search name"`
+	Where string `arg:"" help:"Record query expression"`
+}
+
+func (s *SearchSave) Run(c *config.Config) error {
+	return libip.SearchSave(c, s.Name, s.Where)
+}
+
+type SearchRemove struct {
+	Name string `arg:"" help:"Search name"`
+}
+
+func (s *SearchRemove) Run(c *config.Config) error {
+	return libip.SearchRemove(c, s.Name)
+}
+
+type SearchCmd struct {
+	List SearchList   `cmd:"" default:"1" help:"List saved searches"`
+	Save SearchSave   `cmd:"" help:"Save a record query for ip list --saved and the TUI"`
+	Rm   SearchRemove `cmd:"" help:"Remove a saved search"`
+}
+
+type AuditCmd struct {
+	Since   string `help:"Only entries since a duration ago (24h), date or RFC 3339 time"`
+	Network string `short:"n" help:"Only entries for this network"`
+	Record  string `help:"Only entries for this address"`
+	User    string `help:"Only entries made by this user"`
+	Format  string `short:"f" enum:"table,json" default:"table" help:"Output format (table, json)"`
+}
+
+func (a *AuditCmd) Run(c *config.Config) error {
+	return libip.Audit(c, a.Since, a.Network, a.Record, a.User, a.Format)
+}
+
+type Fsck struct {
+	Repair bool `help:"Rebuild the secondary indexes from the records"`
+}
+
+func (f *Fsck) Run(c *config.Config) error {
+	return libip.Fsck(c, f.Repair)
+}
+
+type IpCmd struct {
+	Network string `short:"n" default:"default" help:"Network to operate on"`
+
+	List    IpList    `cmd:"" default:"1" help:"List address records"`
+	Show    IpShow    `cmd:"" help:"Show an address record"`
+	Find    IpFind    `cmd:"" help:"Find the address record holding a hostname or MAC address"`
+	Add     IpAdd     `cmd:"" help:"Add an address record"`
+	Alloc   IpAlloc   `cmd:"" help:"Allocate the next available address"`
+	Release IpRelease `cmd:"" help:"Release an address into quarantine"`
+	State   IpState   `cmd:"" help:"Change an address's lifecycle state"`
+	Tag     IpTag     `cmd:"" help:"Add or remove tags of an address record"`
+	Field   IpField   `cmd:"" help:"Set custom fields of an address record"`
+	Rm      IpRemove  `cmd:"" help:"Remove a free or reserved address record"`
+	Import  IpImport  `cmd:"" help:"Import address records from CSV"`
+}
+
 var cli struct {
-	App   AppCmd `cmd:"" default:"withargs" help:"Main App."`
-	Info  Info   `cmd:"" help:"Show system info"`
-	Test  Test   `cmd:"" help:"Run in test mode"`
-	Reset Reset  `cmd:"" help:"Reset app db"`
-	Debug bool   `help:"Enable debug mode."`
+	App      AppCmd      `cmd:"" default:"withargs" help:"Main App."`
+	Info     Info        `cmd:"" help:"Show system info"`
+	Test     Test        `cmd:"" help:"Run in test mode"`
+	Reset    Reset       `cmd:"" help:"Reset app db"`
+	Network  NetworkCmd  `cmd:"" help:"Manage networks"`
+	Template TemplateCmd `cmd:"" help:"Manage subnet templates"`
+	Ip       IpCmd       `cmd:"" help:"Manage address records"`
+	Report   ReportCmd   `cmd:"" help:"Capacity reports"`
+	Alert    AlertCmd    `cmd:"" help:"Utilisation alerts"`
+	Audit    AuditCmd    `cmd:"" help:"Show the audit log of changes"`
+	Search   SearchCmd   `cmd:"" help:"Manage saved record searches"`
+	Fsck     Fsck        `cmd:"" help:"Check the database for inconsistencies"`
+	Debug    bool        `help:"Enable debug mode."`
+	Config   string      `type:"path" default:"${config_path}" help:"Config file to load."`
 }
 
 func main() {
-	ctx := kong.Parse(&cli)
+	ctx := kong.Parse(&cli, kong.Vars{"config_path": config.DefaultPath()})
 
-	c := config.NewConfig()
+	c, err := config.Load(cli.Config)
+	ctx.FatalIfErrorf(err)
 	c.Debug = cli.Debug
+	ctx.FatalIfErrorf(styles.Load(c.Theme))
 
-	err := ctx.Run(c)
+	err = ctx.Run(c)
 	ctx.FatalIfErrorf(err)
 }